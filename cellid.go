@@ -0,0 +1,268 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// CellID packs a grid cell into a uint64. The top 8 bits are the Morton code
+// of the 100km square's column and row, followed by a step per level as in
+// QuadKey: 2 bits choosing a quadrant, then 5 bits choosing one of the five
+// by five cells in it. A 1 bit follows the last step and the rest are 0, so
+// every cell within a cell has an id in its [RangeMin, RangeMax], and ids
+// sort by square, then by area within it. The zero CellID is invalid.
+type CellID uint64
+
+const (
+	cellSquareBits = 8
+	cellMaxLevel   = maxDigits // two steps per digit pair
+	cellQuadBits   = 2
+	cellCellBits   = 5
+)
+
+// squareNames are the National Grid squares by Morton code.
+var squareNames = func() [256]string {
+	var names [256]string
+	for k, v := range NationalGridSquares {
+		names[morton(int(v[0]), int(v[1]))] = k
+	}
+
+	return names
+}()
+
+// cellStepBits returns the number of bits used by the first level steps.
+func cellStepBits(level int) int {
+	return (level/2)*(cellQuadBits+cellCellBits) + (level%2)*cellQuadBits
+}
+
+// cellLevel returns the level of a precision, 0 for 100km, 2 for 10km, 3 for
+// a quadrant and so on, the odd levels below being half decimal cells.
+func cellLevel(precision float64) (int, error) {
+	size := SquareSize
+	for level := 0; level <= cellMaxLevel; level++ {
+		if precision == size {
+			return level, nil
+		}
+		if level%2 == 0 {
+			size /= 2
+		} else {
+			size /= 5
+		}
+	}
+
+	return 0, fmt.Errorf("unsupported precision %v", precision)
+}
+
+// CellIDFromEastingNorthing returns the id of the cell containing an OSGB36
+// easting / northing at one of the Precisions.
+func CellIDFromEastingNorthing(east, north, precision float64) (CellID, error) {
+	level, err := cellLevel(precision)
+	if err != nil {
+		return 0, err
+	}
+
+	col := math.Floor(east / SquareSize)
+	row := math.Floor(north / SquareSize)
+
+	if col < 0 || row < 0 || col > 15 || row > 15 {
+		return 0, fmt.Errorf("%v, %v is outside the national grid", east, north)
+	}
+
+	code := morton(int(col), int(row))
+	if squareNames[code] == "" {
+		return 0, fmt.Errorf("%v, %v is outside the national grid", east, north)
+	}
+
+	// whole metres within the square
+	x := int(east - col*SquareSize)
+	y := int(north - row*SquareSize)
+
+	id := uint64(code) << (64 - cellSquareBits)
+	pos := 64 - cellSquareBits
+	size := int(SquareSize)
+
+	for l := 0; l < level; l++ {
+		if l%2 == 0 {
+			size /= 2
+			q := x/size%2 + 2*(y/size%2)
+			pos -= cellQuadBits
+			id |= uint64(q) << pos
+		} else {
+			size /= 5
+			c := x/size%5 + 5*(y/size%5)
+			pos -= cellCellBits
+			id |= uint64(c) << pos
+		}
+	}
+
+	return CellID(id | 1<<(pos-1)), nil
+}
+
+// CellIDFromGridRef returns the id of a grid ref's cell.
+func CellIDFromGridRef(g GridRef) (CellID, error) {
+	b, err := g.Bounds()
+	if err != nil {
+		return 0, err
+	}
+
+	return CellIDFromEastingNorthing(b.Xmin, b.Ymin, g.Precision())
+}
+
+// lsb returns the sentinel bit.
+func (c CellID) lsb() uint64 {
+	return uint64(c) & -uint64(c)
+}
+
+// Level returns the number of steps below the 100km square, -1 when invalid.
+func (c CellID) Level() int {
+	lsb := c.lsb()
+	if lsb == 0 {
+		return -1
+	}
+
+	for level := 0; level <= cellMaxLevel; level++ {
+		if lsb == 1<<(64-cellSquareBits-cellStepBits(level)-1) {
+			return level
+		}
+	}
+
+	return -1
+}
+
+// IsValid reports whether the id is of a cell on the National Grid.
+func (c CellID) IsValid() bool {
+	return c.Level() >= 0 && squareNames[uint64(c)>>(64-cellSquareBits)] != ""
+}
+
+// Precision returns the width of the cell in metres.
+func (c CellID) Precision() float64 {
+	level := c.Level()
+	size := SquareSize
+
+	for l := 0; l < level; l++ {
+		if l%2 == 0 {
+			size /= 2
+		} else {
+			size /= 5
+		}
+	}
+
+	return size
+}
+
+// Bounds returns the OSGB36 extent of the cell.
+func (c CellID) Bounds() Bounds {
+	level := c.Level()
+	code := int(uint64(c) >> (64 - cellSquareBits))
+	col, row := unmorton(code)
+
+	x := col * int(SquareSize)
+	y := row * int(SquareSize)
+	pos := 64 - cellSquareBits
+	size := int(SquareSize)
+
+	for l := 0; l < level; l++ {
+		if l%2 == 0 {
+			size /= 2
+			pos -= cellQuadBits
+			q := int(uint64(c) >> pos & (1<<cellQuadBits - 1))
+			x += q % 2 * size
+			y += q / 2 * size
+		} else {
+			size /= 5
+			pos -= cellCellBits
+			v := int(uint64(c) >> pos & (1<<cellCellBits - 1))
+			x += v % 5 * size
+			y += v / 5 * size
+		}
+	}
+
+	return Bounds{
+		Xmin: float64(x),
+		Xmax: float64(x + size),
+		Ymin: float64(y),
+		Ymax: float64(y + size),
+	}
+}
+
+// Centre returns the centre of the cell.
+func (c CellID) Centre() EastingNorthing {
+	b := c.Bounds()
+
+	return EastingNorthing{
+		Easting:  b.Xmin + (b.Xmax-b.Xmin)/2,
+		Northing: b.Ymin + (b.Ymax-b.Ymin)/2,
+	}
+}
+
+// Parent returns the id of the cell containing this one at level, which must
+// be no deeper than the cell's own level.
+func (c CellID) Parent(level int) CellID {
+	lsb := uint64(1) << (64 - cellSquareBits - cellStepBits(level) - 1)
+
+	return CellID(uint64(c)&-lsb | lsb)
+}
+
+// Children returns the cells one level below this one: the 4 quadrants of a
+// cell at an even level, else its 5 by 5 cells.
+func (c CellID) Children() []CellID {
+	level := c.Level()
+	if level < 0 || level >= cellMaxLevel {
+		return nil
+	}
+
+	n, width := 4, cellQuadBits
+	if level%2 == 1 {
+		n, width = 25, cellCellBits
+	}
+
+	s := bits.TrailingZeros64(uint64(c))
+	base := uint64(c) &^ c.lsb()
+
+	children := make([]CellID, n)
+	for i := range children {
+		children[i] = CellID(base | uint64(i)<<(s+1-width) | 1<<(s-width))
+	}
+
+	return children
+}
+
+// RangeMin returns the smallest id of the cells within this one.
+func (c CellID) RangeMin() CellID {
+	return CellID(uint64(c) - (c.lsb() - 1))
+}
+
+// RangeMax returns the largest id of the cells within this one.
+func (c CellID) RangeMax() CellID {
+	return CellID(uint64(c) + (c.lsb() - 1))
+}
+
+// Contains reports whether o is this cell or within it.
+func (c CellID) Contains(o CellID) bool {
+	return o >= c.RangeMin() && o <= c.RangeMax()
+}
+
+// GridRef returns the grid ref of the cell, a quadrant ref such as "SDNE" or
+// "SD8710NE" for the levels between digits.
+func (c CellID) GridRef() (GridRef, error) {
+	var g GridRef
+
+	if !c.IsValid() {
+		return g, fmt.Errorf("invalid cell id %#x", uint64(c))
+	}
+
+	b := c.Bounds()
+
+	return GetGridRef(b.Xmin, b.Ymin, c.Precision())
+}
+
+func (c CellID) String() string {
+	g, err := c.GridRef()
+	if err != nil {
+		return "CellID(0x" + strconv.FormatUint(uint64(c), 16) + ")"
+	}
+
+	return g.String()
+}
@@ -0,0 +1,171 @@
+package nationalgrid
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCellIDRoundTrip(t *testing.T) {
+	refs := []string{"SD", "SD81", "SD91NW", "SD8710", "SD871102", "SD8722110715", "SV00", "HP99"}
+
+	for _, ref := range refs {
+		g, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, err := CellIDFromGridRef(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !id.IsValid() {
+			t.Fatalf("%v expected a valid id, got %#x", ref, uint64(id))
+		}
+
+		actual, err := id.GridRef()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g != actual {
+			t.Fatalf("%v expected %+v, got %+v", ref, g, actual)
+		}
+
+		expectedBounds, err := g.Bounds()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.Bounds() != expectedBounds {
+			t.Fatalf("%v expected %+v, got %+v", ref, expectedBounds, id.Bounds())
+		}
+
+		if id.String() != ref {
+			t.Fatalf("expected %v, got %v", ref, id.String())
+		}
+	}
+}
+
+func TestCellIDFromEastingNorthing(t *testing.T) {
+	tests := map[string]struct {
+		east, north, precision float64
+		expected               string
+		err                    bool
+	}{
+		"10km":          {387654, 410432, SubSquareSize, "SD81", false},
+		"quadrant":      {394000, 416000, QuadrantSize, "SD91NW", false},
+		"1m":            {387654.7, 410432.2, MetreSize, "SD8765410432", false},
+		"off grid":      {-1, 0, SubSquareSize, "", true},
+		"no square":     {650000, 50000, SubSquareSize, "", true},
+		"bad precision": {387654, 410432, 2000, "", true},
+	}
+
+	for name, tt := range tests {
+		id, err := CellIDFromEastingNorthing(tt.east, tt.north, tt.precision)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%v expected an error, got %v", name, id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id.String() != tt.expected {
+			t.Fatalf("%v expected %v, got %v", name, tt.expected, id)
+		}
+	}
+}
+
+func TestCellIDHierarchy(t *testing.T) {
+	id, err := CellIDFromEastingNorthing(387654, 410432, MetreSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"SD", "SDSE", "SD81", "SD81SE", "SD8710", "SD8710SE", "SD876104", "SD876104SE", "SD87651043", "SD87651043SW", "SD8765410432"}
+
+	for level, ref := range expected {
+		parent := id.Parent(level)
+
+		if parent.Level() != level {
+			t.Fatalf("expected level %v, got %v", level, parent.Level())
+		}
+
+		if !parent.Contains(id) {
+			t.Fatalf("expected %v to contain %v", parent, id)
+		}
+
+		if parent.String() != ref {
+			t.Fatalf("expected %v, got %v", ref, parent)
+		}
+
+		b := parent.Bounds()
+		if b.Xmax-b.Xmin != parent.Precision() {
+			t.Fatalf("expected width %v, got %+v", parent.Precision(), b)
+		}
+	}
+
+	other, err := CellIDFromEastingNorthing(397654, 410432, MetreSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id.Parent(2).Contains(other) {
+		t.Fatalf("expected SD81 not to contain %v", other)
+	}
+}
+
+func TestCellIDOrder(t *testing.T) {
+	// the cells of SD81 sort together, between those of other squares
+	g, err := ParseGridRef("SD81")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent, err := CellIDFromGridRef(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []CellID
+	for _, e := range []float64{370000, 381234, 389999, 390000} {
+		for _, p := range []float64{KmSquareSize, HectareSize, MetreSize} {
+			id, err := CellIDFromEastingNorthing(e, 415000, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	inside := false
+	ended := false
+	for _, id := range ids {
+		switch {
+		case parent.Contains(id) && ended:
+			t.Fatalf("expected the cells of %v to be contiguous, got %v", parent, ids)
+		case parent.Contains(id):
+			inside = true
+		case inside:
+			ended = true
+		}
+	}
+}
+
+func TestCellIDAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		id, _ := CellIDFromEastingNorthing(387654, 410432, MetreSize)
+		_ = id.Parent(4).Contains(id)
+		_ = id.Bounds()
+		_ = id.Precision()
+	})
+
+	if allocs != 0 {
+		t.Fatalf("expected 0 allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,300 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CellSet is a set of grid cells at mixed precisions. It is kept normalised:
+// cells are sorted by CellID, none is within another, and a cell whose
+// children are all present replaces them, so four sibling quadrants become
+// their 10km square and 100 sibling 1km squares theirs. The zero CellSet is
+// empty and ready to use.
+type CellSet struct {
+	ids []CellID
+}
+
+// NewCellSet returns the set of the given cells.
+func NewCellSet(refs ...GridRef) (CellSet, error) {
+	ids := make([]CellID, 0, len(refs))
+
+	for _, ref := range refs {
+		id, err := CellIDFromGridRef(ref)
+		if err != nil {
+			return CellSet{}, err
+		}
+		ids = append(ids, id)
+	}
+
+	return CellSet{ids: normaliseCellIDs(ids)}, nil
+}
+
+// CellSetFromSubSquares returns the set of 10km sub-squares in a
+// GetSubSquares result.
+func CellSetFromSubSquares(subSquares map[string][]int) (CellSet, error) {
+	var refs []GridRef
+
+	for square, indexes := range subSquares {
+		for _, i := range indexes {
+			if i < 0 || i > 99 {
+				return CellSet{}, fmt.Errorf("invalid subsquare %v %v", square, i)
+			}
+
+			refs = append(refs, GridRef{
+				Square:    strings.ToUpper(square),
+				SubSquare: fmt.Sprintf("%02d", i),
+			})
+		}
+	}
+
+	return NewCellSet(refs...)
+}
+
+// Add adds a cell to the set, leaving any copies of it unchanged.
+func (s *CellSet) Add(ref GridRef) error {
+	id, err := CellIDFromGridRef(ref)
+	if err != nil {
+		return err
+	}
+
+	// normalising works in place, so copies must not share the array
+	ids := make([]CellID, 0, len(s.ids)+1)
+	ids = append(ids, s.ids...)
+
+	s.ids = normaliseCellIDs(append(ids, id))
+
+	return nil
+}
+
+// Len returns the number of cells in the normalised set.
+func (s CellSet) Len() int {
+	return len(s.ids)
+}
+
+// CellIDs returns the ids of the normalised set in order.
+func (s CellSet) CellIDs() []CellID {
+	return append([]CellID(nil), s.ids...)
+}
+
+// GridRefs returns the cells of the normalised set in CellID order, merged
+// quadrants such as half a 100km square as quadrant refs.
+func (s CellSet) GridRefs() []GridRef {
+	refs := make([]GridRef, 0, len(s.ids))
+
+	for _, id := range s.ids {
+		// the ids of a set are always valid
+		ref, _ := id.GridRef()
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+// Expand returns every cell of the set at a precision, in CellID order. It
+// fails if the set holds cells finer than the precision.
+func (s CellSet) Expand(precision float64) ([]GridRef, error) {
+	err := ValidatePrecision(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := cellLevel(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []GridRef
+
+	var expand func(id CellID) error
+	expand = func(id CellID) error {
+		if id.Level() > level {
+			return fmt.Errorf("%v is finer than %v", id, FormatPrecision(precision))
+		}
+
+		if id.Level() == level {
+			ref, err := id.GridRef()
+			if err != nil {
+				return err
+			}
+			refs = append(refs, ref)
+
+			return nil
+		}
+
+		for _, child := range id.Children() {
+			err := expand(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, id := range s.ids {
+		err := expand(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return refs, nil
+}
+
+// Contains reports whether a cell is wholly within the set.
+func (s CellSet) Contains(ref GridRef) bool {
+	id, err := CellIDFromGridRef(ref)
+	if err != nil {
+		return false
+	}
+
+	return s.containsCell(id)
+}
+
+// Intersects reports whether any part of a cell is in the set.
+func (s CellSet) Intersects(ref GridRef) bool {
+	id, err := CellIDFromGridRef(ref)
+	if err != nil {
+		return false
+	}
+
+	return s.intersectsCell(id)
+}
+
+// Union returns the cells in either set.
+func (s CellSet) Union(o CellSet) CellSet {
+	ids := make([]CellID, 0, len(s.ids)+len(o.ids))
+	ids = append(ids, s.ids...)
+	ids = append(ids, o.ids...)
+
+	return CellSet{ids: normaliseCellIDs(ids)}
+}
+
+// Intersection returns the cells in both sets.
+func (s CellSet) Intersection(o CellSet) CellSet {
+	var ids []CellID
+
+	i, j := 0, 0
+	for i < len(s.ids) && j < len(o.ids) {
+		a, b := s.ids[i], o.ids[j]
+
+		switch {
+		case a.Contains(b):
+			ids = append(ids, b)
+			j++
+		case b.Contains(a):
+			ids = append(ids, a)
+			i++
+		case a < b:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return CellSet{ids: normaliseCellIDs(ids)}
+}
+
+// Difference returns the cells of s that are not in o, splitting cells of s
+// that are partly covered by o.
+func (s CellSet) Difference(o CellSet) CellSet {
+	var ids []CellID
+
+	var subtract func(id CellID)
+	subtract = func(id CellID) {
+		switch {
+		case !o.intersectsCell(id):
+			ids = append(ids, id)
+		case o.containsCell(id):
+		default:
+			for _, child := range id.Children() {
+				subtract(child)
+			}
+		}
+	}
+
+	for _, id := range s.ids {
+		subtract(id)
+	}
+
+	return CellSet{ids: normaliseCellIDs(ids)}
+}
+
+// containsCell reports whether a cell of the set contains id.
+func (s CellSet) containsCell(id CellID) bool {
+	i := sort.Search(len(s.ids), func(i int) bool { return s.ids[i] > id })
+
+	if i < len(s.ids) && s.ids[i].RangeMin() <= id {
+		return true
+	}
+
+	return i > 0 && s.ids[i-1].RangeMax() >= id
+}
+
+// intersectsCell reports whether a cell of the set overlaps id.
+func (s CellSet) intersectsCell(id CellID) bool {
+	i := sort.Search(len(s.ids), func(i int) bool { return s.ids[i] >= id.RangeMin() })
+
+	if i < len(s.ids) && s.ids[i].RangeMin() <= id.RangeMax() {
+		return true
+	}
+
+	return i > 0 && s.ids[i-1].RangeMax() >= id.RangeMin()
+}
+
+// normaliseCellIDs sorts ids, drops those within another and replaces
+// complete sets of children by their parent.
+func normaliseCellIDs(ids []CellID) []CellID {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	out := ids[:0]
+
+	for _, id := range ids {
+		if len(out) > 0 && out[len(out)-1].Contains(id) {
+			continue
+		}
+
+		for len(out) > 0 && id.Contains(out[len(out)-1]) {
+			out = out[:len(out)-1]
+		}
+
+		// merge siblings into their parent while the family is complete
+		for {
+			level := id.Level()
+			if level == 0 {
+				break
+			}
+
+			n := 4
+			if level%2 == 0 {
+				n = 25
+			}
+
+			if len(out) < n-1 {
+				break
+			}
+
+			parent := id.Parent(level - 1)
+
+			complete := true
+			for _, sibling := range out[len(out)-(n-1):] {
+				if sibling.Level() != level || sibling.Parent(level-1) != parent {
+					complete = false
+					break
+				}
+			}
+
+			if !complete {
+				break
+			}
+
+			out = out[:len(out)-(n-1)]
+			id = parent
+		}
+
+		out = append(out, id)
+	}
+
+	return out
+}
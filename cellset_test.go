@@ -0,0 +1,205 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"testing"
+)
+
+func mustCellSet(t *testing.T, refs ...string) CellSet {
+	t.Helper()
+
+	var gridRefs []GridRef
+	for _, ref := range refs {
+		g, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gridRefs = append(gridRefs, g)
+	}
+
+	s, err := NewCellSet(gridRefs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func cellSetStrings(s CellSet) []string {
+	var refs []string
+	for _, ref := range s.GridRefs() {
+		refs = append(refs, ref.String())
+	}
+
+	return refs
+}
+
+func TestCellSetNormalise(t *testing.T) {
+	var km []string
+	for e := 0; e < 10; e++ {
+		for n := 0; n < 10; n++ {
+			km = append(km, fmt.Sprintf("SD8%d1%d", e, n))
+		}
+	}
+
+	tests := map[string]struct {
+		refs     []string
+		expected []string
+	}{
+		"quadrants": {
+			refs:     []string{"SD81NE", "SD81NW", "SD81SE", "SD81SW"},
+			expected: []string{"SD81"},
+		},
+		"km squares": {
+			refs:     km,
+			expected: []string{"SD81"},
+		},
+		"contained": {
+			refs:     []string{"SD8710", "SD81", "SD81NE"},
+			expected: []string{"SD81"},
+		},
+		"duplicates": {
+			refs:     []string{"SD8710", "SD8710"},
+			expected: []string{"SD8710"},
+		},
+		"incomplete": {
+			refs:     []string{"SD81NE", "SD81NW", "SD81SE"},
+			expected: []string{"SD81SE", "SD81NW", "SD81NE"},
+		},
+		"column": {
+			refs:     km[:5],
+			expected: []string{"SD8010", "SD8011", "SD8012", "SD8013", "SD8014"},
+		},
+	}
+
+	for name, tt := range tests {
+		actual := cellSetStrings(mustCellSet(t, tt.refs...))
+
+		if fmt.Sprint(tt.expected) != fmt.Sprint(actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCellSetAddCopy(t *testing.T) {
+	// SD8110 is dropped inside SD81, leaving spare capacity to append into
+	s := mustCellSet(t, "SD81", "SD83", "SD8110")
+
+	c := s
+	err := c.Add(GridRef{Square: "SD", SubSquare: "82"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"SD81", "SD83"}
+	if actual := cellSetStrings(s); fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+
+	expected = []string{"SD81", "SD82", "SD83"}
+	if actual := cellSetStrings(c); fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCellSetQuadrantMerge(t *testing.T) {
+	var refs []string
+	for e := 0; e < 5; e++ {
+		for n := 0; n < 5; n++ {
+			refs = append(refs, fmt.Sprintf("SD8%d1%d", e, n))
+		}
+	}
+
+	actual := cellSetStrings(mustCellSet(t, refs...))
+	expected := []string{"SD81SW"}
+
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCellSetHalfSquare(t *testing.T) {
+	// 25 10km squares merge into a 50km quadrant
+	var refs []string
+	for e := 0; e < 5; e++ {
+		for n := 0; n < 5; n++ {
+			refs = append(refs, fmt.Sprintf("SD%d%d", e, n))
+		}
+	}
+
+	s := mustCellSet(t, refs...)
+
+	if s.Len() != 1 || s.CellIDs()[0].Precision() != SquareSize/2 {
+		t.Fatalf("expected a single 50km cell, got %+v", s.CellIDs())
+	}
+
+	expected := []string{"SDSW"}
+	actual := cellSetStrings(s)
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCellSetOperations(t *testing.T) {
+	a := mustCellSet(t, "SD81", "SD92")
+	b := mustCellSet(t, "SD8710", "SD92NE", "SD93")
+
+	tests := map[string]struct {
+		actual   CellSet
+		expected []string
+	}{
+		"union":        {a.Union(b), []string{"SD81", "SD92", "SD93"}},
+		"intersection": {a.Intersection(b), []string{"SD8710", "SD92NE"}},
+		"difference":   {a.Difference(mustCellSet(t, "SD81NE", "SD81NW", "SD92")), []string{"SD81SW", "SD81SE"}},
+		"empty":        {a.Intersection(mustCellSet(t, "NT27")), nil},
+	}
+
+	for name, tt := range tests {
+		actual := cellSetStrings(tt.actual)
+
+		if fmt.Sprint(tt.expected) != fmt.Sprint(actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.expected, actual)
+		}
+	}
+
+	diff := a.Difference(b)
+	if diff.Contains(GridRef{Square: "SD", SubSquare: "8710"}) || !diff.Contains(GridRef{Square: "SD", SubSquare: "8711"}) {
+		t.Fatalf("expected SD8710 to be removed, got %+v", cellSetStrings(diff))
+	}
+
+	if !diff.Intersects(GridRef{Square: "SD", SubSquare: "81"}) || diff.Intersects(GridRef{Square: "SD", SubSquare: "92", Quadrant: NE}) {
+		t.Fatalf("unexpected intersections in %+v", cellSetStrings(diff))
+	}
+}
+
+func TestCellSetExpand(t *testing.T) {
+	s := mustCellSet(t, "SD81NE", "SD8710")
+
+	refs, err := s.Expand(KmSquareSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(refs) != 26 {
+		t.Fatalf("expected 26 cells, got %v", len(refs))
+	}
+
+	_, err = s.Expand(SubSquareSize)
+	if err == nil {
+		t.Fatalf("expected an error expanding to a coarser precision")
+	}
+}
+
+func TestCellSetFromSubSquares(t *testing.T) {
+	s, err := CellSetFromSubSquares(map[string][]int{"sd": {87, 98}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"SD87", "SD98"}
+	actual := cellSetStrings(s)
+
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+var errConversionFailed = errors.New("one or more values failed to convert")
+
+type record struct {
+	Input    string  `json:"input"`
+	Ref      string  `json:"ref,omitempty"`
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	Error    string  `json:"error,omitempty"`
+}
+
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func convertCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var files fileList
+
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", "auto", "input type: auto, ref, en or latlon")
+	precision := fs.String("precision", "10km", "output grid ref precision: 100km, 10km, 1km, 100m, 10m or 1m, or a quadrant size: 50km, 5km, 500m, 50m or 5m")
+	format := fs.String("format", "text", "output format: text, json or csv")
+	fs.Var(&files, "file", "read values from a file, - for stdin (repeatable)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	p, err := nationalgrid.ParsePrecision(*precision)
+	if err != nil {
+		return err
+	}
+
+	w, err := newRecordWriter(*format, stdout)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+
+	convertAll := func(values []string) error {
+		for _, v := range values {
+			r := convert(v, *from, p)
+			if r.Error != "" {
+				failed = true
+			}
+
+			err := w.Write(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	switch {
+	case fs.NArg() > 0:
+		err = convertAll(fs.Args())
+		if err != nil {
+			return err
+		}
+
+	case len(files) > 0:
+		for _, name := range files {
+			err := convertFile(name, stdin, convertAll)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		err = convertLines(stdin, convertAll)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if failed {
+		return errConversionFailed
+	}
+
+	return nil
+}
+
+func convertFile(name string, stdin io.Reader, fn func([]string) error) error {
+	if name == "-" {
+		return convertLines(stdin, fn)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return convertLines(f, fn)
+}
+
+// convertLines passes each non blank, non comment line of r to fn.
+func convertLines(r io.Reader, fn func([]string) error) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		err := fn([]string{line})
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func convert(input, from string, precision float64) record {
+	r := record{
+		Input: input,
+	}
+
+	var err error
+
+	if from == "auto" {
+		from = detectType(input)
+	}
+
+	switch from {
+	case "ref":
+		err = fromRef(&r, precision)
+	case "en":
+		err = fromEastingNorthing(&r, precision)
+	case "latlon":
+		err = fromLatLon(&r, precision)
+	default:
+		err = fmt.Errorf("unsupported input type %v", from)
+	}
+
+	if err != nil {
+		return record{
+			Input: input,
+			Error: err.Error(),
+		}
+	}
+
+	return r
+}
+
+// detectType treats values starting with a letter as grid refs and pairs of
+// numbers small enough to be degrees as lat / lon.
+func detectType(input string) string {
+	input = strings.TrimSpace(input)
+	if input != "" && (input[0] < '0' || input[0] > '9') && input[0] != '-' && input[0] != '+' && input[0] != '.' {
+		return "ref"
+	}
+
+	a, b, err := parsePair(input)
+	if err == nil && a >= -90 && a <= 90 && b >= -180 && b <= 180 {
+		return "latlon"
+	}
+
+	return "en"
+}
+
+func parsePair(input string) (float64, float64, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("expected two numbers, got %q", input)
+	}
+
+	a, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number %q", fields[0])
+	}
+
+	b, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number %q", fields[1])
+	}
+
+	return a, b, nil
+}
+
+func fromRef(r *record, precision float64) error {
+	ref := strings.ToUpper(strings.ReplaceAll(r.Input, " ", ""))
+
+	gridRef, err := nationalgrid.ParseGridRef(ref)
+	if err != nil {
+		return err
+	}
+
+	err = nationalgrid.ValidateSquare(gridRef.Square)
+	if err != nil {
+		return err
+	}
+
+	east, north, err := nationalgrid.GetGridLatLon(ref)
+	if err != nil {
+		return err
+	}
+
+	// a ref cannot be made more precise than it was given
+	if gridRef.Precision() > precision {
+		precision = gridRef.Precision()
+	}
+
+	return fill(r, east, north, precision)
+}
+
+func fromEastingNorthing(r *record, precision float64) error {
+	east, north, err := parsePair(r.Input)
+	if err != nil {
+		return err
+	}
+
+	return fill(r, east, north, precision)
+}
+
+func fromLatLon(r *record, precision float64) error {
+	lat, lon, err := parsePair(r.Input)
+	if err != nil {
+		return err
+	}
+
+	l := nationalgrid.Location{
+		Type: nationalgrid.WGS84.String(),
+		LatLon: nationalgrid.LatLon{
+			Lat: lat,
+			Lon: lon,
+		},
+	}
+
+	// ToOSGB36 returns the easting as Lat and the northing as Lon
+	osgb := l.ToOSGB36()
+
+	return fill(r, osgb.LatLon.Lat, osgb.LatLon.Lon, precision)
+}
+
+func fill(r *record, east, north, precision float64) error {
+	gridRef, err := nationalgrid.GetGridRef(east, north, precision)
+	if err != nil {
+		return err
+	}
+
+	l := nationalgrid.Location{
+		Type: nationalgrid.OSGB36.String(),
+		LatLon: nationalgrid.LatLon{
+			Lat: east,
+			Lon: north,
+		},
+	}
+	latLon := l.ToWGS84()
+
+	r.Ref = gridRef.String()
+	r.Easting = east
+	r.Northing = north
+	r.Lat = latLon.Lat
+	r.Lon = latLon.Lon
+
+	return nil
+}
+
+type recordWriter interface {
+	Write(r record) error
+	Flush() error
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case "text":
+		return &textWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported format %v", format)
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatDegrees(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Write(r record) error {
+	var err error
+
+	if r.Error != "" {
+		_, err = fmt.Fprintf(t.w, "%v\terror: %v\n", r.Input, r.Error)
+	} else {
+		_, err = fmt.Fprintf(t.w, "%v\t%v\t%v %v\t%v %v\n",
+			r.Input, r.Ref,
+			formatCoord(r.Easting), formatCoord(r.Northing),
+			formatDegrees(r.Lat), formatDegrees(r.Lon),
+		)
+	}
+
+	return err
+}
+
+func (t *textWriter) Flush() error {
+	return nil
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonWriter) Write(r record) error {
+	return j.enc.Encode(r)
+}
+
+func (j *jsonWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(r record) error {
+	if !c.header {
+		c.header = true
+
+		err := c.w.Write([]string{"input", "ref", "easting", "northing", "lat", "lon", "error"})
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Error != "" {
+		return c.w.Write([]string{r.Input, "", "", "", "", "", r.Error})
+	}
+
+	return c.w.Write([]string{
+		r.Input,
+		r.Ref,
+		formatCoord(r.Easting),
+		formatCoord(r.Northing),
+		formatDegrees(r.Lat),
+		formatDegrees(r.Lon),
+		"",
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
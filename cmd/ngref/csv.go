@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func csvCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("csv", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	refColumn := fs.String("ref-column", "", "header of the grid ref column")
+	latColumn := fs.String("lat-column", "", "header of the WGS84 latitude column")
+	lonColumn := fs.String("lon-column", "", "header of the WGS84 longitude column")
+	precisions := fs.String("precision", "10km", "comma separated grid ref precisions to append: 100km, 10km, 1km, 100m, 10m or 1m, or a quadrant size: 50km, 5km, 500m, 50m or 5m")
+	output := fs.String("o", "", "write to a file rather than stdout")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts := nationalgrid.CSVOptions{
+		RefColumn: *refColumn,
+		LatColumn: *latColumn,
+		LonColumn: *lonColumn,
+	}
+
+	for _, s := range strings.Split(*precisions, ",") {
+		p, err := nationalgrid.ParsePrecision(s)
+		if err != nil {
+			return err
+		}
+		opts.Precisions = append(opts.Precisions, p)
+	}
+
+	r := stdin
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if *output == "" {
+		return nationalgrid.ConvertCSV(r, stdout, opts)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	// a failed close can mean the output was not all written
+	err = nationalgrid.ConvertCSV(r, f, opts)
+	cerr := f.Close()
+	if err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+// Command ngref converts between Ordnance Survey grid refs, OSGB36 eastings /
+// northings and WGS84 lat / lon.
+//
+//	ngref [convert] [flags] [value ...]
+//	ngref csv [flags] [file]
+//	ngref serve [-addr host:port]
+//
+// Values are read from the command line, the files given with -file, or
+// stdin when neither is given. The csv command streams a csv, appending
+// converted columns to each row, and serve exposes the conversions over
+// HTTP, see package server.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: ngref [command] [flags] [value ...]
+
+commands:
+  convert  convert grid refs, eastings / northings or lat / lon (default)
+  csv      append converted columns to a csv
+  serve    serve conversions over http
+
+run "ngref <command> -h" for the flags of each command
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmd := "convert"
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "convert", "csv", "serve":
+			cmd = args[0]
+			args = args[1:]
+		case "help", "-h", "-help", "--help":
+			fmt.Fprint(stderr, usage)
+			return 0
+		}
+	}
+
+	var err error
+
+	switch cmd {
+	case "convert":
+		err = convertCmd(args, stdin, stdout, stderr)
+	case "csv":
+		err = csvCmd(args, stdin, stdout, stderr)
+	case "serve":
+		err = serveCmd(args, stdout, stderr)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "ngref:", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"SD91NW", "392500,417500"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %v: %v", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", lines)
+	}
+
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if fields[1] != "SD91" || fields[2] != "392500 417500" {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestConvertJSONPrecision(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	stdin := strings.NewReader("# sightings\n53.646,-2.114\n\nSD\n")
+
+	code := run([]string{"convert", "-format", "json", "-precision", "5km"}, stdin, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %v: %v", code, stderr.String())
+	}
+
+	dec := json.NewDecoder(&stdout)
+
+	var r record
+	err := dec.Decode(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Ref != "SD91NW" {
+		t.Fatalf("expected %v, got %+v", "SD91NW", r)
+	}
+
+	err = dec.Decode(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a 100km ref cannot be made more precise
+	if r.Ref != "SD" || r.Easting != 350000 || r.Northing != 450000 {
+		t.Fatalf("unexpected record %+v", r)
+	}
+}
+
+func TestConvertCSVErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-format", "csv", "SD00XX", "SD00"}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit 1, got %v", code)
+	}
+
+	expected := "input,ref,easting,northing,lat,lon,error\n"
+	if !strings.HasPrefix(stdout.String(), expected) {
+		t.Fatalf("expected header %q, got %q", expected, stdout.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %v", lines)
+	}
+
+	if !strings.HasPrefix(lines[1], "SD00XX,,,,,,") {
+		t.Fatalf("expected error row, got %q", lines[1])
+	}
+
+	if !strings.HasPrefix(lines[2], "SD00,SD00,305000,405000,") {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	tests := map[string]string{
+		"SD91NW":        "ref",
+		"sd 91":         "ref",
+		"392500,417500": "en",
+		"53.6 -2.1":     "latlon",
+		"-2.1,53.6":     "latlon",
+	}
+
+	for input, expected := range tests {
+		actual := detectType(input)
+		if expected != actual {
+			t.Fatalf("%v expected %v, got %v", input, expected, actual)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	stdin := strings.NewReader("ref\nSD91NW\n")
+
+	code := run([]string{"csv", "-ref-column", "ref", "-precision", "10km,5km"}, stdin, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %v: %v", code, stderr.String())
+	}
+
+	expected := "ref,easting,northing,lat,lon,ref_10km,ref_5km,error\n"
+	if !strings.HasPrefix(stdout.String(), expected) {
+		t.Fatalf("expected header %q, got %q", expected, stdout.String())
+	}
+
+	if !strings.Contains(stdout.String(), ",SD91,SD91NW,\n") {
+		t.Fatalf("unexpected output %q", stdout.String())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rockwell-uk/go-nationalgrid/render"
+	"github.com/rockwell-uk/go-nationalgrid/server"
+)
+
+func serveCmd(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server.NewHandler())
+	mux.Handle("/tiles/", http.StripPrefix("/tiles", render.NewTileHandler(render.DefaultTileOptions())))
+	mux.Handle("/mvt/", http.StripPrefix("/mvt", render.NewVectorTileHandler(render.DefaultTileOptions())))
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	fmt.Fprintf(stdout, "listening on %v\n", *addr)
+
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,75 @@
+package nationalgrid
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	geos "github.com/twpayne/go-geos"
+)
+
+// run with -race, every exported function called here must be safe for
+// concurrent use.
+func TestConcurrentCallers(t *testing.T) {
+	expectedSubSquares := map[string][]int{"sd": {81, 91}}
+	expectedEast, expectedNorth := 385000.0, 415000.0
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				subSquares := GetSubSquares(geos.NewBounds(387221, 410715, 392221, 415715))
+				if !reflect.DeepEqual(expectedSubSquares, subSquares) {
+					t.Errorf("expected %+v, got %+v", expectedSubSquares, subSquares)
+					return
+				}
+
+				east, north, err := GetGridLatLon("SD81")
+				if err != nil {
+					errs <- err
+					return
+				}
+				if east != expectedEast || north != expectedNorth {
+					t.Errorf("expected %v %v, got %v %v", expectedEast, expectedNorth, east, north)
+					return
+				}
+
+				ref, err := GetGridRef(east, north, KmSquareSize)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				s, err := NewCellSet(ref)
+				if err != nil {
+					errs <- err
+					return
+				}
+				_ = s.Outline().ToWKT()
+
+				var buf bytes.Buffer
+				err = WriteGeoJSON(&buf, []GridRef{ref}, WGS84)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
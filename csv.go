@@ -0,0 +1,300 @@
+package nationalgrid
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type CSVOptions struct {
+	// RefColumn is the header of a grid ref column, taking priority over
+	// LatColumn and LonColumn when set.
+	RefColumn string
+	// LatColumn and LonColumn are the headers of WGS84 lat / lon columns.
+	LatColumn string
+	LonColumn string
+	// Precisions are the cell sizes a ref_<size> column is appended for, each
+	// accepted by ValidatePrecision so quadrant sizes are allowed. A column is
+	// left empty where it is finer than a grid ref being read.
+	Precisions []float64
+	// Comma is the field delimiter, ',' when zero.
+	Comma rune
+}
+
+// ConvertCSV streams a csv with a header row from r to w, appending easting,
+// northing, lat, lon, a ref column per precision and an error column. Rows
+// that fail to parse or convert have the error column set rather than
+// aborting, and rows are padded or cut to the header's width.
+func ConvertCSV(r io.Reader, w io.Writer, opts CSVOptions) error {
+	for _, p := range opts.Precisions {
+		err := ValidatePrecision(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	lines := &csvLines{r: r, first: 1}
+	cr := csv.NewReader(lines)
+	cr.FieldsPerRecord = -1
+	cw := csv.NewWriter(w)
+
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+		cw.Comma = opts.Comma
+	}
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	refCol, latCol, lonCol := -1, -1, -1
+
+	switch {
+	case opts.RefColumn != "":
+		i, ok := columns[opts.RefColumn]
+		if !ok {
+			return fmt.Errorf("column not found %v", opts.RefColumn)
+		}
+		refCol = i
+
+	case opts.LatColumn != "" && opts.LonColumn != "":
+		i, ok := columns[opts.LatColumn]
+		if !ok {
+			return fmt.Errorf("column not found %v", opts.LatColumn)
+		}
+		latCol = i
+
+		i, ok = columns[opts.LonColumn]
+		if !ok {
+			return fmt.Errorf("column not found %v", opts.LonColumn)
+		}
+		lonCol = i
+
+	default:
+		return fmt.Errorf("either a ref column or lat and lon columns are required")
+	}
+
+	out := append([]string{}, header...)
+	out = append(out, "easting", "northing", "lat", "lon")
+	for _, p := range opts.Precisions {
+		out = append(out, "ref_"+FormatPrecision(p))
+	}
+	out = append(out, "error")
+
+	err = cw.Write(out)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return err
+		}
+
+		if err != nil {
+			// keep the row as it was rather than the fields parsed before the error
+			row = csvLazyFields(lines.raw(parseErr.StartLine, parseErr.Line), cr.Comma)
+			lines.discard(parseErr.Line + 1)
+		} else {
+			line, _ := cr.FieldPos(0)
+			lines.discard(line)
+		}
+
+		// pad or truncate rows to the header so the appended columns line up
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		row = row[:len(header)]
+
+		var added []string
+		if err != nil {
+			added = csvRowError(opts, err)
+		} else {
+			added = csvConvertRow(row, refCol, latCol, lonCol, opts)
+		}
+
+		err = cw.Write(append(row, added...))
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func csvConvertRow(row []string, refCol, latCol, lonCol int, opts CSVOptions) []string {
+	var east, north, lat, lon float64
+	var inputRef *GridRef
+
+	if refCol >= 0 {
+		ref := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(row[refCol]), " ", ""))
+
+		gridRef, err := ParseGridRef(ref)
+		if err != nil {
+			return csvRowError(opts, err)
+		}
+
+		err = ValidateSquare(gridRef.Square)
+		if err != nil {
+			return csvRowError(opts, err)
+		}
+
+		east, north, err = getGridCoordCenter(gridRef)
+		if err != nil {
+			return csvRowError(opts, err)
+		}
+
+		lon, lat = osgb36ToWGS84(east, north)
+		inputRef = &gridRef
+	} else {
+		var err error
+
+		lat, err = strconv.ParseFloat(strings.TrimSpace(row[latCol]), 64)
+		if err != nil {
+			return csvRowError(opts, fmt.Errorf("invalid lat %q", row[latCol]))
+		}
+
+		lon, err = strconv.ParseFloat(strings.TrimSpace(row[lonCol]), 64)
+		if err != nil {
+			return csvRowError(opts, fmt.Errorf("invalid lon %q", row[lonCol]))
+		}
+
+		l := Location{
+			Type: WGS84.String(),
+			LatLon: LatLon{
+				Lat: lat,
+				Lon: lon,
+			},
+		}
+
+		err = validateWGS84(l.LatLon)
+		if err != nil {
+			return csvRowError(opts, err)
+		}
+
+		osgb := l.ToOSGB36()
+		east, north = osgb.LatLon.Lat, osgb.LatLon.Lon
+	}
+
+	added := []string{
+		strconv.FormatFloat(east, 'f', -1, 64),
+		strconv.FormatFloat(north, 'f', -1, 64),
+		strconv.FormatFloat(lat, 'f', 6, 64),
+		strconv.FormatFloat(lon, 'f', 6, 64),
+	}
+
+	for _, p := range opts.Precisions {
+		// a ref cannot be made more precise than it was given
+		if inputRef != nil && p < inputRef.Precision() {
+			added = append(added, "")
+			continue
+		}
+
+		gridRef, err := GetGridRef(east, north, p)
+		if err != nil {
+			return csvRowError(opts, err)
+		}
+		added = append(added, gridRef.String())
+	}
+
+	return append(added, "")
+}
+
+// csvRowError returns empty converted columns with the error column set.
+func csvRowError(opts CSVOptions, err error) []string {
+	added := make([]string, 4+len(opts.Precisions)+1)
+	added[len(added)-1] = err.Error()
+
+	return added
+}
+
+// csvLazyFields splits the raw text of a row that failed to parse, reading
+// stray quotes literally, or returns it as a single field if it still fails.
+func csvLazyFields(raw string, comma rune) []string {
+	r := csv.NewReader(strings.NewReader(raw))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	row, err := r.Read()
+	if err != nil {
+		return []string{raw}
+	}
+
+	return row
+}
+
+// csvLines passes reads through, keeping the physical lines read so that a
+// row that fails to parse can be written out as it was.
+type csvLines struct {
+	r io.Reader
+	// first is the line number, from 1, of lines[0]
+	first   int
+	lines   []string
+	partial []byte
+}
+
+func (l *csvLines) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+
+	for _, b := range p[:n] {
+		if b == '\n' {
+			l.lines = append(l.lines, strings.TrimSuffix(string(l.partial), "\r"))
+			l.partial = l.partial[:0]
+			continue
+		}
+		l.partial = append(l.partial, b)
+	}
+
+	return n, err
+}
+
+// raw returns lines start to end joined, the line still being read included.
+func (l *csvLines) raw(start, end int) string {
+	var parts []string
+
+	for n := start; n <= end; n++ {
+		i := n - l.first
+
+		switch {
+		case i >= 0 && i < len(l.lines):
+			parts = append(parts, l.lines[i])
+		case i == len(l.lines) && len(l.partial) > 0:
+			parts = append(parts, strings.TrimSuffix(string(l.partial), "\r"))
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// discard drops the lines before line.
+func (l *csvLines) discard(line int) {
+	i := line - l.first
+	if i <= 0 {
+		return
+	}
+
+	if i > len(l.lines) {
+		i = len(l.lines)
+	}
+
+	l.lines = l.lines[i:]
+	l.first += i
+}
@@ -0,0 +1,141 @@
+package nationalgrid
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestConvertCSVRefColumn(t *testing.T) {
+	in := "id,ref,note\n1,SD91NW,ok\n2,sd 00,lower case\n3,SD00XX,bad\n4,SD\n"
+
+	var out bytes.Buffer
+	err := ConvertCSV(strings.NewReader(in), &out, CSVOptions{
+		RefColumn:  "ref",
+		Precisions: []float64{SquareSize, QuadrantSize},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedHeader := []string{"id", "ref", "note", "easting", "northing", "lat", "lon", "ref_100km", "ref_5km", "error"}
+	if strings.Join(rows[0], ",") != strings.Join(expectedHeader, ",") {
+		t.Fatalf("expected header %v, got %v", expectedHeader, rows[0])
+	}
+
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %v", len(rows))
+	}
+
+	tests := map[int]struct {
+		East, North, Ref100, Ref5, Error string
+	}{
+		1: {"392500", "417500", "SD", "SD91NW", ""},
+		2: {"305000", "405000", "SD", "", ""},
+		4: {"350000", "450000", "SD", "", ""},
+	}
+
+	for i, tt := range tests {
+		row := rows[i]
+		actual := struct {
+			East, North, Ref100, Ref5, Error string
+		}{row[3], row[4], row[7], row[8], row[9]}
+
+		if actual != tt {
+			t.Fatalf("row %v expected %+v, got %+v", i, tt, actual)
+		}
+	}
+
+	if rows[3][9] == "" || rows[3][3] != "" {
+		t.Fatalf("expected row 3 to fail, got %v", rows[3])
+	}
+}
+
+func TestConvertCSVLatLonColumns(t *testing.T) {
+	in := "lat;lon\n53.646;-2.114\nx;1\n"
+
+	var out bytes.Buffer
+	err := ConvertCSV(strings.NewReader(in), &out, CSVOptions{
+		LatColumn:  "lat",
+		LonColumn:  "lon",
+		Precisions: []float64{SubSquareSize},
+		Comma:      ';',
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := csv.NewReader(&out)
+	r.Comma = ';'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[1][6] != "SD91" || rows[1][7] != "" {
+		t.Fatalf("unexpected row %v", rows[1])
+	}
+
+	if rows[2][7] == "" {
+		t.Fatalf("expected error for row %v", rows[2])
+	}
+}
+
+func TestConvertCSVMissingColumn(t *testing.T) {
+	var out bytes.Buffer
+	err := ConvertCSV(strings.NewReader("a,b\n"), &out, CSVOptions{
+		RefColumn: "ref",
+	})
+	if err == nil {
+		t.Fatal("expected error for missing column")
+	}
+}
+
+func TestConvertCSVMalformedRows(t *testing.T) {
+	in := "id,ref,note\n1,SD\"91,bare quote\n2,SD91NW,ok,extra\n3,SD00\n4,\"SD91, quoted\",\"bare\"quote\"\n"
+
+	var out bytes.Buffer
+	err := ConvertCSV(strings.NewReader(in), &out, CSVOptions{
+		RefColumn:  "ref",
+		Precisions: []float64{SquareSize},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := csv.NewReader(&out)
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %v", len(rows))
+	}
+
+	// the unparseable row is kept as it was, with the error set
+	if strings.Join(rows[1][:3], "|") != `1|SD"91|bare quote` || rows[1][8] == "" {
+		t.Fatalf("expected the raw row with an error, got %q", rows[1])
+	}
+
+	// the long row is cut to the header so the appended columns line up
+	expected := []string{"2", "SD91NW", "ok", "392500", "417500"}
+	if strings.Join(rows[2][:5], ",") != strings.Join(expected, ",") || rows[2][7] != "SD" || rows[2][8] != "" {
+		t.Fatalf("expected %v..., got %q", expected, rows[2])
+	}
+
+	if rows[3][7] != "SD" || rows[3][8] != "" {
+		t.Fatalf("expected row 3 to convert, got %q", rows[3])
+	}
+
+	// quoted fields of an unparseable row are not split on their commas
+	if strings.Join(rows[4][:3], "|") != `4|SD91, quoted|bare"quote` || rows[4][8] == "" {
+		t.Fatalf("expected the quoted fields with an error, got %q", rows[4])
+	}
+}
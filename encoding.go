@@ -0,0 +1,193 @@
+package nationalgrid
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func (g GridRef) String() string {
+	return g.Square + g.SubSquare + g.Quadrant.String()
+}
+
+// validateKnownGridRef checks a grid ref is well formed and in one of the
+// NationalGridSquares.
+func validateKnownGridRef(ref string) error {
+	err := ValidateGridRef(ref)
+	if err != nil {
+		return err
+	}
+
+	g, err := ParseGridRef(ref)
+	if err != nil {
+		return err
+	}
+
+	return ValidateSquare(g.Square)
+}
+
+func (g GridRef) MarshalText() ([]byte, error) {
+	if g.Square == "" {
+		return []byte{}, nil
+	}
+
+	ref := g.String()
+
+	err := validateKnownGridRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(ref), nil
+}
+
+func (g *GridRef) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*g = GridRef{}
+		return nil
+	}
+
+	gridRef, err := ParseGridRef(string(text))
+	if err != nil {
+		return err
+	}
+
+	err = ValidateSquare(gridRef.Square)
+	if err != nil {
+		return err
+	}
+
+	*g = gridRef
+
+	return nil
+}
+
+func (g GridRef) MarshalJSON() ([]byte, error) {
+	text, err := g.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(text))
+}
+
+func (g *GridRef) UnmarshalJSON(data []byte) error {
+	var ref string
+
+	err := json.Unmarshal(data, &ref)
+	if err != nil {
+		return fmt.Errorf("a grid ref must be a json string %v", string(data))
+	}
+
+	return g.UnmarshalText([]byte(ref))
+}
+
+type locationJSON struct {
+	Type    string   `json:"type"`
+	Lat     *float64 `json:"lat,omitempty"`
+	Lon     *float64 `json:"lon,omitempty"`
+	GridRef string   `json:"gridRef,omitempty"`
+}
+
+func (c Location) MarshalJSON() ([]byte, error) {
+	v := locationJSON{
+		Type: c.Type,
+	}
+
+	switch c.Type {
+	case WGS84.String(), OSGB36.String():
+		lat, lon := c.LatLon.Lat, c.LatLon.Lon
+		v.Lat = &lat
+		v.Lon = &lon
+
+	case NATIONALGRID.String():
+		err := validateKnownGridRef(c.GridRef)
+		if err != nil {
+			return nil, err
+		}
+		v.GridRef = c.GridRef
+
+	default:
+		return nil, fmt.Errorf("unknown location type %v", c.Type)
+	}
+
+	return json.Marshal(v)
+}
+
+func (c *Location) UnmarshalJSON(data []byte) error {
+	var v locationJSON
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	switch v.Type {
+	case WGS84.String(), OSGB36.String():
+		if v.Lat == nil || v.Lon == nil {
+			return fmt.Errorf("a %v location requires lat and lon", v.Type)
+		}
+
+		latLon := LatLon{
+			Lat: *v.Lat,
+			Lon: *v.Lon,
+		}
+
+		if v.Type == WGS84.String() {
+			err := validateWGS84(latLon)
+			if err != nil {
+				return err
+			}
+		}
+
+		*c = Location{
+			Type:   v.Type,
+			LatLon: latLon,
+		}
+
+	case NATIONALGRID.String():
+		err := validateKnownGridRef(v.GridRef)
+		if err != nil {
+			return err
+		}
+
+		*c = Location{
+			Type:    v.Type,
+			GridRef: v.GridRef,
+		}
+
+	default:
+		return fmt.Errorf("unknown location type %v", v.Type)
+	}
+
+	return nil
+}
+
+func (b *Bounds) UnmarshalJSON(data []byte) error {
+	type bounds Bounds
+	var v bounds
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	if v.Xmin > v.Xmax || v.Ymin > v.Ymax {
+		return fmt.Errorf("invalid bounds %+v", v)
+	}
+
+	*b = Bounds(v)
+
+	return nil
+}
+
+func validateWGS84(l LatLon) error {
+	if l.Lat < -90 || l.Lat > 90 {
+		return fmt.Errorf("latitude out of range %v", l.Lat)
+	}
+
+	if l.Lon < -180 || l.Lon > 180 {
+		return fmt.Errorf("longitude out of range %v", l.Lon)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package nationalgrid
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGridRefJSON(t *testing.T) {
+	tests := map[string]struct {
+		Ref      GridRef
+		Expected string
+	}{
+		"square": {
+			Ref: GridRef{
+				Square: "SD",
+			},
+			Expected: `"SD"`,
+		},
+		"subsquare": {
+			Ref: GridRef{
+				Square:    "SD",
+				SubSquare: "87",
+			},
+			Expected: `"SD87"`,
+		},
+		"quadrant": {
+			Ref: GridRef{
+				Square:    "SD",
+				SubSquare: "87",
+				Quadrant:  NE,
+			},
+			Expected: `"SD87NE"`,
+		},
+	}
+
+	for name, tt := range tests {
+		b, err := json.Marshal(tt.Ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != tt.Expected {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, string(b))
+		}
+
+		var actual GridRef
+		err = json.Unmarshal(b, &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.Ref, actual) {
+			t.Fatalf("%v expected %#v, got %#v", name, tt.Ref, actual)
+		}
+	}
+}
+
+func TestGridRefUnmarshalInvalid(t *testing.T) {
+	tests := []string{
+		`"SD00XX"`,
+		`"00XX"`,
+		`"S"`,
+		`"ZZ87"`,
+		`{"Square":"SD"}`,
+	}
+
+	for _, data := range tests {
+		var g GridRef
+		err := json.Unmarshal([]byte(data), &g)
+		if err == nil {
+			t.Fatalf("expected error for %v", data)
+		}
+	}
+}
+
+func TestGridRefMarshalInvalid(t *testing.T) {
+	tests := []interface{}{
+		GridRef{Square: "ZZ", SubSquare: "87"},
+		Location{Type: NATIONALGRID.String(), GridRef: "ZZ87"},
+	}
+
+	for _, v := range tests {
+		_, err := json.Marshal(v)
+		if err == nil {
+			t.Fatalf("expected error for %+v", v)
+		}
+	}
+}
+
+func TestGridRefText(t *testing.T) {
+	type row struct {
+		Refs map[GridRef]int
+	}
+
+	in := row{
+		Refs: map[GridRef]int{
+			{Square: "SD", SubSquare: "87"}: 1,
+		},
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"Refs":{"SD87":1}}`
+	if string(b) != expected {
+		t.Fatalf("expected %v, got %v", expected, string(b))
+	}
+
+	var out row
+	err = json.Unmarshal(b, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+}
+
+func TestLocationJSON(t *testing.T) {
+	tests := map[string]struct {
+		Location Location
+		Expected string
+	}{
+		"wgs84": {
+			Location: Location{
+				Type: WGS84.String(),
+				LatLon: LatLon{
+					Lat: 54.1,
+					Lon: -2.2,
+				},
+			},
+			Expected: `{"type":"WGS84","lat":54.1,"lon":-2.2}`,
+		},
+		"osgb36": {
+			Location: Location{
+				Type: OSGB36.String(),
+				LatLon: LatLon{
+					Lat: 387221,
+					Lon: 410715,
+				},
+			},
+			Expected: `{"type":"OSGB36","lat":387221,"lon":410715}`,
+		},
+		"nationalgrid": {
+			Location: Location{
+				Type:    NATIONALGRID.String(),
+				GridRef: "SD87NE",
+			},
+			Expected: `{"type":"NATIONALGRID","gridRef":"SD87NE"}`,
+		},
+	}
+
+	for name, tt := range tests {
+		b, err := json.Marshal(tt.Location)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != tt.Expected {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, string(b))
+		}
+
+		var actual Location
+		err = json.Unmarshal(b, &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.Location, actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.Location, actual)
+		}
+	}
+}
+
+func TestLocationUnmarshalInvalid(t *testing.T) {
+	tests := []string{
+		`{"type":"WGS84","lat":91,"lon":0}`,
+		`{"type":"WGS84","lat":54}`,
+		`{"type":"NATIONALGRID","gridRef":"SD00XX"}`,
+		`{"type":"NATIONALGRID","gridRef":"ZZ87"}`,
+		`{"type":"MERCATOR","lat":0,"lon":0}`,
+	}
+
+	for _, data := range tests {
+		var l Location
+		err := json.Unmarshal([]byte(data), &l)
+		if err == nil {
+			t.Fatalf("expected error for %v", data)
+		}
+	}
+}
+
+func TestBoundsJSON(t *testing.T) {
+	b := Bounds{
+		Xmin: 300000,
+		Xmax: 400000,
+		Ymin: 400000,
+		Ymax: 500000,
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"xmin":300000,"xmax":400000,"ymin":400000,"ymax":500000}`
+	if string(data) != expected {
+		t.Fatalf("expected %v, got %v", expected, string(data))
+	}
+
+	var actual Bounds
+	err = json.Unmarshal(data, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(b, actual) {
+		t.Fatalf("expected %+v, got %+v", b, actual)
+	}
+
+	err = json.Unmarshal([]byte(`{"xmin":1,"xmax":0,"ymin":0,"ymax":1}`), &actual)
+	if err == nil {
+		t.Fatal("expected error for inverted bounds")
+	}
+}
@@ -0,0 +1,367 @@
+package nationalgrid
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	geos "github.com/twpayne/go-geos"
+)
+
+// the legacy named crs member, only written for OSGB36 output as RFC 7946
+// mandates WGS84 lon / lat.
+const epsg27700URN = "urn:ogc:def:crs:EPSG::27700"
+
+type geoJSONCRS struct {
+	Type       string            `json:"type"`
+	Properties map[string]string `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	CRS      *geoJSONCRS      `json:"crs,omitempty"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPolygon    `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONPolygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	Ref       string     `json:"ref"`
+	Precision float64    `json:"precision"`
+	Centre    [2]float64 `json:"centre"`
+}
+
+// generic geojson object, only the members needed to find geometries are decoded.
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Features    []geoJSONObject `json:"features"`
+}
+
+// WriteGeoJSON writes a FeatureCollection with one polygon per grid ref, in
+// either OSGB36 (EPSG:27700) or WGS84 coordinates.
+func WriteGeoJSON(w io.Writer, refs []GridRef, crs LocationType) error {
+	project, err := geoJSONProjection(crs)
+	if err != nil {
+		return err
+	}
+
+	fc := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]geoJSONFeature, 0, len(refs)),
+	}
+
+	if crs == OSGB36 {
+		fc.CRS = &geoJSONCRS{
+			Type: "name",
+			Properties: map[string]string{
+				"name": epsg27700URN,
+			},
+		}
+	}
+
+	for _, ref := range refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return err
+		}
+
+		east, north, err := getGridCoordCenter(ref)
+		if err != nil {
+			return err
+		}
+
+		ring := [][2]float64{
+			project(b.Xmin, b.Ymin),
+			project(b.Xmax, b.Ymin),
+			project(b.Xmax, b.Ymax),
+			project(b.Xmin, b.Ymax),
+			project(b.Xmin, b.Ymin),
+		}
+
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONPolygon{
+				Type:        "Polygon",
+				Coordinates: [][][2]float64{ring},
+			},
+			Properties: geoJSONProperties{
+				Ref:       ref.String(),
+				Precision: ref.Precision(),
+				Centre:    project(east, north),
+			},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(fc)
+}
+
+func geoJSONProjection(crs LocationType) (func(x, y float64) [2]float64, error) {
+	switch crs {
+	case OSGB36:
+		return func(x, y float64) [2]float64 {
+			return [2]float64{x, y}
+		}, nil
+
+	case WGS84:
+		return func(x, y float64) [2]float64 {
+			lon, lat := osgb36ToWGS84(x, y)
+			return [2]float64{lon, lat}
+		}, nil
+
+	}
+
+	return nil, fmt.Errorf("unsupported geojson crs %v", crs)
+}
+
+// ReadGeoJSONCoverage reads a GeoJSON geometry, feature or feature collection
+// and returns the squares / subsquares its geometries touch, keyed as per
+// GetSubSquares. Points cover the cells they fall in, lines the cells their
+// segments cross and polygons also the cells inside them. Coordinates are in
+// either OSGB36 or WGS84.
+func ReadGeoJSONCoverage(r io.Reader, crs LocationType) (map[string][]int, error) {
+	if crs != OSGB36 && crs != WGS84 {
+		return nil, fmt.Errorf("unsupported geojson crs %v", crs)
+	}
+
+	var obj geoJSONObject
+
+	err := json.NewDecoder(r).Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []geoJSONPart
+
+	err = geoJSONParts(obj, crs, &parts)
+	if err != nil {
+		return nil, err
+	}
+
+	subSquares := make(map[string][]int)
+
+	for _, part := range parts {
+		b := part.bounds()
+
+		for k, candidates := range GetSubSquares(geos.NewBounds(b.Xmin, b.Ymin, b.Xmax, b.Ymax)) {
+			var covered []int
+
+			for _, i := range candidates {
+				cell := subSquareCoords[strings.ToUpper(k)][i]
+
+				if part.intersects(Bounds{Xmin: cell.MinX, Xmax: cell.MaxX, Ymin: cell.MinY, Ymax: cell.MaxY}) {
+					covered = append(covered, i)
+				}
+			}
+
+			if len(covered) > 0 {
+				mergeSubSquares(subSquares, map[string][]int{k: covered})
+			}
+		}
+	}
+
+	return subSquares, nil
+}
+
+// geoJSONPart is a single point, line or polygon in OSGB36, a point being a
+// ring of one position.
+type geoJSONPart struct {
+	rings [][][2]float64
+	area  bool
+}
+
+func (p geoJSONPart) bounds() Bounds {
+	b := Bounds{
+		Xmin: math.Inf(1),
+		Ymin: math.Inf(1),
+		Xmax: math.Inf(-1),
+		Ymax: math.Inf(-1),
+	}
+
+	for _, ring := range p.rings {
+		for _, c := range ring {
+			b.Xmin = math.Min(b.Xmin, c[0])
+			b.Ymin = math.Min(b.Ymin, c[1])
+			b.Xmax = math.Max(b.Xmax, c[0])
+			b.Ymax = math.Max(b.Ymax, c[1])
+		}
+	}
+
+	return b
+}
+
+// intersects reports whether the part touches b. Points and lines count
+// along an edge, areas only where they overlap the inside of b.
+func (p geoJSONPart) intersects(b Bounds) bool {
+	crosses := b.IntersectsSegment
+	if p.area {
+		crosses = b.InteriorIntersectsSegment
+	}
+
+	for _, ring := range p.rings {
+		if len(ring) == 1 && b.ContainsPoint(ring[0][0], ring[0][1]) {
+			return true
+		}
+
+		for i := 0; i+1 < len(ring); i++ {
+			if crosses(ring[i], ring[i+1]) {
+				return true
+			}
+		}
+
+		// polygon rings may be left open
+		if p.area && len(ring) > 2 && crosses(ring[len(ring)-1], ring[0]) {
+			return true
+		}
+	}
+
+	// a cell wholly inside a polygon
+	return p.area && Polygon(p.rings).Contains(b.Xmin+(b.Xmax-b.Xmin)/2, b.Ymin+(b.Ymax-b.Ymin)/2)
+}
+
+// geoJSONParts appends each geometry part in obj, the members of multi
+// geometries being treated separately.
+func geoJSONParts(obj geoJSONObject, crs LocationType, parts *[]geoJSONPart) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			err := geoJSONParts(f, crs, parts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil
+		}
+		return geoJSONParts(*obj.Geometry, crs, parts)
+
+	case "GeometryCollection":
+		for _, g := range obj.Geometries {
+			err := geoJSONParts(g, crs, parts)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "Point", "LineString", "Polygon":
+		var coords interface{}
+		err := json.Unmarshal(obj.Coordinates, &coords)
+		if err != nil {
+			return err
+		}
+
+		rings, err := positionRings(coords, crs)
+		if err != nil {
+			return err
+		}
+		if len(rings) > 0 {
+			*parts = append(*parts, geoJSONPart{rings: rings, area: obj.Type == "Polygon"})
+		}
+
+	case "MultiPoint", "MultiLineString", "MultiPolygon":
+		var coords []interface{}
+		err := json.Unmarshal(obj.Coordinates, &coords)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range coords {
+			rings, err := positionRings(c, crs)
+			if err != nil {
+				return err
+			}
+			if len(rings) > 0 {
+				*parts = append(*parts, geoJSONPart{rings: rings, area: obj.Type == "MultiPolygon"})
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported geojson type %v", obj.Type)
+	}
+
+	return nil
+}
+
+// positionRings returns the positions of a point, line or polygon in OSGB36,
+// grouped by the array holding them. Empty coordinates have no rings.
+func positionRings(coords interface{}, crs LocationType) ([][][2]float64, error) {
+	var rings [][][2]float64
+
+	position := func(items []interface{}) ([2]float64, bool) {
+		if len(items) < 2 {
+			return [2]float64{}, false
+		}
+
+		x, xok := items[0].(float64)
+		y, yok := items[1].(float64)
+		if !xok || !yok {
+			return [2]float64{}, false
+		}
+
+		if crs == WGS84 {
+			x, y = wgs84ToOSGB36(x, y)
+		}
+
+		return [2]float64{x, y}, true
+	}
+
+	var walk func(c interface{}) error
+	walk = func(c interface{}) error {
+		items, ok := c.([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid geojson coordinates %v", c)
+		}
+
+		if p, ok := position(items); ok {
+			rings = append(rings, [][2]float64{p})
+			return nil
+		}
+
+		var ring [][2]float64
+
+		for _, item := range items {
+			sub, ok := item.([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid geojson coordinates %v", item)
+			}
+
+			if p, ok := position(sub); ok {
+				ring = append(ring, p)
+				continue
+			}
+
+			err := walk(sub)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(ring) > 0 {
+			rings = append(rings, ring)
+		}
+
+		return nil
+	}
+
+	err := walk(coords)
+	if err != nil {
+		return nil, err
+	}
+
+	return rings, nil
+}
@@ -0,0 +1,196 @@
+package nationalgrid
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteGeoJSON(t *testing.T) {
+	refs := []GridRef{
+		{Square: "SD"},
+		{Square: "SD", SubSquare: "91", Quadrant: NW},
+	}
+
+	var buf bytes.Buffer
+	err := WriteGeoJSON(&buf, refs, OSGB36)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc geoJSONFeatureCollection
+	err = json.Unmarshal(buf.Bytes(), &fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fc.CRS == nil || fc.CRS.Properties["name"] != epsg27700URN {
+		t.Fatalf("expected crs %v, got %+v", epsg27700URN, fc.CRS)
+	}
+
+	expected := []geoJSONFeature{
+		{
+			Type: "Feature",
+			Geometry: geoJSONPolygon{
+				Type: "Polygon",
+				Coordinates: [][][2]float64{{
+					{300000, 400000},
+					{400000, 400000},
+					{400000, 500000},
+					{300000, 500000},
+					{300000, 400000},
+				}},
+			},
+			Properties: geoJSONProperties{
+				Ref:       "SD",
+				Precision: SquareSize,
+				Centre:    [2]float64{350000, 450000},
+			},
+		},
+		{
+			Type: "Feature",
+			Geometry: geoJSONPolygon{
+				Type: "Polygon",
+				Coordinates: [][][2]float64{{
+					{390000, 415000},
+					{395000, 415000},
+					{395000, 420000},
+					{390000, 420000},
+					{390000, 415000},
+				}},
+			},
+			Properties: geoJSONProperties{
+				Ref:       "SD91NW",
+				Precision: QuadrantSize,
+				Centre:    [2]float64{392500, 417500},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, fc.Features) {
+		t.Fatalf("expected %+v, got %+v", expected, fc.Features)
+	}
+}
+
+func TestWriteGeoJSONWGS84(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGeoJSON(&buf, []GridRef{{Square: "SD", SubSquare: "91"}}, WGS84)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc geoJSONFeatureCollection
+	err = json.Unmarshal(buf.Bytes(), &fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fc.CRS != nil {
+		t.Fatalf("expected no crs member, got %+v", fc.CRS)
+	}
+
+	// SD91 is in the Pennines, roughly 2.1W 53.6N
+	centre := fc.Features[0].Properties.Centre
+	if math.Abs(centre[0]+2.08) > 0.1 || math.Abs(centre[1]-53.63) > 0.1 {
+		t.Fatalf("unexpected centre %v", centre)
+	}
+}
+
+func TestReadGeoJSONCoverage(t *testing.T) {
+	tests := map[string]struct {
+		GeoJSON  string
+		CRS      LocationType
+		Expected map[string][]int
+	}{
+		"polygon": {
+			GeoJSON: `{"type":"Polygon","coordinates":[[[387221.19,410715.07],[392221.19,410715.07],[392221.19,415715.07],[387221.19,415715.07],[387221.19,410715.07]]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {81, 91},
+			},
+		},
+		"feature collection": {
+			GeoJSON: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[2500,2500]},"properties":{}},
+				{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[302500,402500],[392500,417500]]},"properties":{}},
+				{"type":"Feature","geometry":null,"properties":{}}
+			]}`,
+			CRS: OSGB36,
+			Expected: map[string][]int{
+				"sv": {0},
+				"sd": {0, 91},
+			},
+		},
+		"diagonal line": {
+			GeoJSON: `{"type":"LineString","coordinates":[[300500,400500],[319500,415000]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {0, 10, 11},
+			},
+		},
+		"concave polygon": {
+			GeoJSON: `{"type":"Polygon","coordinates":[[[300500,400500],[319500,400500],[319500,409500],[309500,409500],[309500,419500],[300500,419500],[300500,400500]]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {0, 1, 10},
+			},
+		},
+		"cell inside polygon": {
+			GeoJSON: `{"type":"Polygon","coordinates":[[[300500,400500],[329500,400500],[329500,429500],[300500,429500],[300500,400500]]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {0, 1, 2, 10, 11, 12, 20, 21, 22},
+			},
+		},
+		"polygon on grid lines": {
+			GeoJSON: `{"type":"Polygon","coordinates":[[[380000,410000],[390000,410000],[390000,420000],[380000,420000],[380000,410000]]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {81},
+			},
+		},
+		"line on a grid line": {
+			GeoJSON: `{"type":"LineString","coordinates":[[390000,411000],[390000,419000]]}`,
+			CRS:     OSGB36,
+			Expected: map[string][]int{
+				"sd": {81, 91},
+			},
+		},
+		"wgs84": {
+			GeoJSON: `{"type":"Point","coordinates":[-2.08,53.63]}`,
+			CRS:     WGS84,
+			Expected: map[string][]int{
+				"sd": {91},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := ReadGeoJSONCoverage(strings.NewReader(tt.GeoJSON), tt.CRS)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.Expected, actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.Expected, actual)
+		}
+	}
+}
+
+func TestReadGeoJSONCoverageInvalid(t *testing.T) {
+	tests := []string{
+		`{"type":"Circle","coordinates":[0,0]}`,
+		`{"type":"Point","coordinates":"0,0"}`,
+		`not json`,
+	}
+
+	for _, data := range tests {
+		_, err := ReadGeoJSONCoverage(strings.NewReader(data), OSGB36)
+		if err == nil {
+			t.Fatalf("expected error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package nationalgrid
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SRID is the EPSG code of the OSGB36 / British National Grid projection.
+const SRID = 27700
+
+const (
+	wkbPolygon  = uint32(3)
+	ewkbSRIDBit = uint32(0x20000000)
+	wkbNDR      = byte(1)
+)
+
+// GeometryEncoder writes bounds as WKT, WKB or EWKB polygons with a
+// counter-clockwise exterior ring starting at the bottom left corner. The
+// zero value rounds to whole metres and writes SRID 0, so use
+// NewGeometryEncoder unless that is wanted.
+type GeometryEncoder struct {
+	// Precision is the number of decimal places coordinates are rounded to,
+	// negative for full precision.
+	Precision int
+	// SRID is written to EWKB output.
+	SRID int
+}
+
+// NewGeometryEncoder returns an encoder rounding to precision decimal places,
+// negative for full precision, with output tagged as EPSG:27700.
+func NewGeometryEncoder(precision int) GeometryEncoder {
+	return GeometryEncoder{
+		Precision: precision,
+		SRID:      SRID,
+	}
+}
+
+// DefaultGeometryEncoder writes full precision coordinates tagged as EPSG:27700.
+var DefaultGeometryEncoder = NewGeometryEncoder(-1)
+
+func (e GeometryEncoder) WKT(b Bounds) string {
+	ring := b.ring()
+
+	points := make([]string, 0, len(ring))
+	for _, p := range ring {
+		points = append(points, e.formatOrd(p[0])+" "+e.formatOrd(p[1]))
+	}
+
+	return "POLYGON ((" + strings.Join(points, ", ") + "))"
+}
+
+func (e GeometryEncoder) WKB(b Bounds) []byte {
+	return e.wkb(b, false)
+}
+
+func (e GeometryEncoder) EWKB(b Bounds) []byte {
+	return e.wkb(b, true)
+}
+
+func (e GeometryEncoder) wkb(b Bounds, withSRID bool) []byte {
+	ring := b.ring()
+
+	size := 1 + 4 + 4 + 4 + len(ring)*16
+	if withSRID {
+		size += 4
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, wkbNDR)
+
+	if withSRID {
+		buf = appendUint32(buf, wkbPolygon|ewkbSRIDBit)
+		buf = appendUint32(buf, uint32(e.SRID))
+	} else {
+		buf = appendUint32(buf, wkbPolygon)
+	}
+
+	// one ring
+	buf = appendUint32(buf, 1)
+	buf = appendUint32(buf, uint32(len(ring)))
+
+	for _, p := range ring {
+		buf = appendUint64(buf, math.Float64bits(e.round(p[0])))
+		buf = appendUint64(buf, math.Float64bits(e.round(p[1])))
+	}
+
+	return buf
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+
+	return append(buf, b[:]...)
+}
+
+func (e GeometryEncoder) formatOrd(v float64) string {
+	return strconv.FormatFloat(e.round(v), 'f', e.Precision, 64)
+}
+
+func (e GeometryEncoder) round(v float64) float64 {
+	if e.Precision < 0 {
+		return v
+	}
+
+	p := math.Pow(10, float64(e.Precision))
+
+	return math.Round(v*p) / p
+}
+
+func (b Bounds) ToWKT() string {
+	return DefaultGeometryEncoder.WKT(b)
+}
+
+func (b Bounds) ToWKB() []byte {
+	return DefaultGeometryEncoder.WKB(b)
+}
+
+func (b Bounds) ToEWKB() []byte {
+	return DefaultGeometryEncoder.EWKB(b)
+}
+
+func (g GridRef) ToWKT() (string, error) {
+	b, err := g.Bounds()
+	if err != nil {
+		return "", err
+	}
+
+	return b.ToWKT(), nil
+}
+
+func (g GridRef) ToWKB() ([]byte, error) {
+	b, err := g.Bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.ToWKB(), nil
+}
+
+func (g GridRef) ToEWKB() ([]byte, error) {
+	b, err := g.Bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.ToEWKB(), nil
+}
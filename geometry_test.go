@@ -0,0 +1,199 @@
+package nationalgrid
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func TestBoundsToPolygon(t *testing.T) {
+	b := Bounds{
+		Xmin: 1,
+		Xmax: 3,
+		Ymin: 2,
+		Ymax: 5,
+	}
+
+	expected := "POLYGON ((1 2, 3 2, 3 5, 1 5, 1 2))"
+	actual := b.ToPolygon()
+
+	if expected != actual {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestBoundsIntersectsSegment(t *testing.T) {
+	b := Bounds{
+		Xmin: 0,
+		Xmax: 10,
+		Ymin: 0,
+		Ymax: 10,
+	}
+
+	tests := map[string]struct {
+		A, C     [2]float64
+		Edges    bool
+		Interior bool
+	}{
+		"crossing":   {[2]float64{-5, 5}, [2]float64{15, 5}, true, true},
+		"inside":     {[2]float64{2, 2}, [2]float64{3, 3}, true, true},
+		"along edge": {[2]float64{10, -5}, [2]float64{10, 15}, true, false},
+		"at corner":  {[2]float64{8, 12}, [2]float64{12, 8}, true, false},
+		"to corner":  {[2]float64{15, 15}, [2]float64{10, 10}, true, false},
+		"outside":    {[2]float64{11, 0}, [2]float64{11, 10}, false, false},
+	}
+
+	for name, tt := range tests {
+		if actual := b.IntersectsSegment(tt.A, tt.C); tt.Edges != actual {
+			t.Fatalf("%v expected %v, got %v", name, tt.Edges, actual)
+		}
+
+		if actual := b.InteriorIntersectsSegment(tt.A, tt.C); tt.Interior != actual {
+			t.Fatalf("%v interior expected %v, got %v", name, tt.Interior, actual)
+		}
+	}
+}
+
+func TestNewGeometryEncoder(t *testing.T) {
+	e := NewGeometryEncoder(2)
+
+	if e.Precision != 2 || e.SRID != SRID {
+		t.Fatalf("expected precision 2 and srid %v, got %+v", SRID, e)
+	}
+
+	if DefaultGeometryEncoder != NewGeometryEncoder(-1) {
+		t.Fatalf("expected full precision, got %+v", DefaultGeometryEncoder)
+	}
+}
+
+func TestGeometryEncoderWKT(t *testing.T) {
+	b := Bounds{
+		Xmin: 387221.19853,
+		Xmax: 392221.19853,
+		Ymin: 410715.07842,
+		Ymax: 415715.07842,
+	}
+
+	tests := map[int]string{
+		-1: "POLYGON ((387221.19853 410715.07842, 392221.19853 410715.07842, 392221.19853 415715.07842, 387221.19853 415715.07842, 387221.19853 410715.07842))",
+		0:  "POLYGON ((387221 410715, 392221 410715, 392221 415715, 387221 415715, 387221 410715))",
+		2:  "POLYGON ((387221.20 410715.08, 392221.20 410715.08, 392221.20 415715.08, 387221.20 415715.08, 387221.20 410715.08))",
+	}
+
+	for precision, expected := range tests {
+		e := GeometryEncoder{
+			Precision: precision,
+		}
+
+		actual := e.WKT(b)
+		if expected != actual {
+			t.Fatalf("precision %v\nexpected %v\ngot %v", precision, expected, actual)
+		}
+	}
+}
+
+func TestGridRefToWKT(t *testing.T) {
+	tests := map[string]string{
+		"SD":     "POLYGON ((300000 400000, 400000 400000, 400000 500000, 300000 500000, 300000 400000))",
+		"SD91":   "POLYGON ((390000 410000, 400000 410000, 400000 420000, 390000 420000, 390000 410000))",
+		"SD91NE": "POLYGON ((395000 415000, 400000 415000, 400000 420000, 395000 420000, 395000 415000))",
+	}
+
+	for ref, expected := range tests {
+		gridRef, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := gridRef.ToWKT()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected != actual {
+			t.Fatalf("%v\nexpected %v\ngot %v", ref, expected, actual)
+		}
+	}
+
+	_, err := GridRef{Square: "ZZ"}.ToWKT()
+	if err == nil {
+		t.Fatal("expected error for unknown square")
+	}
+}
+
+func TestGeometryEncoderWKB(t *testing.T) {
+	b := Bounds{
+		Xmin: 0,
+		Xmax: 1,
+		Ymin: 0,
+		Ymax: 1,
+	}
+
+	// POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0)) as little endian WKB
+	expected := "0103000000010000000500000000000000000000000000000000000000000000000000f03f0000000000000000000000000000f03f000000000000f03f0000000000000000000000000000f03f00000000000000000000000000000000"
+
+	actual := hex.EncodeToString(b.ToWKB())
+	if expected != actual {
+		t.Fatalf("\nexpected %v\ngot %v", expected, actual)
+	}
+}
+
+func TestGeometryEncoderEWKB(t *testing.T) {
+	b := Bounds{
+		Xmin: 300000.126,
+		Xmax: 400000,
+		Ymin: 400000,
+		Ymax: 500000,
+	}
+
+	e := NewGeometryEncoder(1)
+	data := e.EWKB(b)
+
+	if len(data) != 1+4+4+4+4+5*16 {
+		t.Fatalf("unexpected length %v", len(data))
+	}
+
+	if data[0] != wkbNDR {
+		t.Fatalf("expected little endian, got %v", data[0])
+	}
+
+	geomType := binary.LittleEndian.Uint32(data[1:5])
+	if geomType != wkbPolygon|ewkbSRIDBit {
+		t.Fatalf("expected type %x, got %x", wkbPolygon|ewkbSRIDBit, geomType)
+	}
+
+	srid := binary.LittleEndian.Uint32(data[5:9])
+	if srid != SRID {
+		t.Fatalf("expected srid %v, got %v", SRID, srid)
+	}
+
+	x := math.Float64frombits(binary.LittleEndian.Uint64(data[17:25]))
+	if x != 300000.1 {
+		t.Fatalf("expected rounded x %v, got %v", 300000.1, x)
+	}
+}
+
+func TestBoundsRingCounterClockwise(t *testing.T) {
+	b := Bounds{
+		Xmin: 300000,
+		Xmax: 400000,
+		Ymin: 400000,
+		Ymax: 500000,
+	}
+	ring := b.ring()
+
+	// shoelace formula, positive for counter-clockwise rings
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+
+	if area <= 0 {
+		t.Fatalf("expected counter-clockwise ring, got signed area %v", area/2)
+	}
+
+	if ring[0] != ring[len(ring)-1] {
+		t.Fatalf("expected closed ring, got %v", ring)
+	}
+}
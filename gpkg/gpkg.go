@@ -0,0 +1,287 @@
+// Package gpkg writes GeoPackages of grid index layers, a polygon layer of
+// the cells at each precision with their refs, in EPSG:27700. The database is
+// written with the pure Go modernc.org/sqlite driver, so no cgo is needed. See
+// https://www.geopackage.org/spec130/.
+package gpkg
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+
+	// registers the "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// applicationID is "GPKG"
+	applicationID = 0x47504b47
+	// userVersion is GeoPackage 1.3.0
+	userVersion = 10300
+)
+
+// spatial reference systems, the undefined ones are required by the spec.
+var spatialRefSys = [][]interface{}{
+	{"Undefined cartesian SRS", int64(-1), "NONE", int64(-1), "undefined", "undefined cartesian coordinate reference system"},
+	{"Undefined geographic SRS", int64(0), "NONE", int64(0), "undefined", "undefined geographic coordinate reference system"},
+	{"WGS 84 geodetic", int64(4326), "EPSG", int64(4326), `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AXIS["Latitude",NORTH],AXIS["Longitude",EAST],AUTHORITY["EPSG","4326"]]`, "longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid"},
+	{"OSGB 1936 / British National Grid", int64(nationalgrid.SRID), "EPSG", int64(nationalgrid.SRID), `PROJCS["OSGB 1936 / British National Grid",GEOGCS["OSGB 1936",DATUM["OSGB_1936",SPHEROID["Airy 1830",6377563.396,299.3249646,AUTHORITY["EPSG","7001"]],TOWGS84[446.448,-125.157,542.06,0.15,0.247,0.842,-20.489],AUTHORITY["EPSG","6277"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4277"]],PROJECTION["Transverse_Mercator"],PARAMETER["latitude_of_origin",49],PARAMETER["central_meridian",-2],PARAMETER["scale_factor",0.9996012717],PARAMETER["false_easting",400000],PARAMETER["false_northing",-100000],UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH],AUTHORITY["EPSG","27700"]]`, "OSGB36 National Grid eastings and northings in metres"},
+}
+
+const (
+	spatialRefSysSQL = `CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition TEXT NOT NULL, description TEXT)`
+
+	contentsSQL = `CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE, description TEXT DEFAULT '', last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')), min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER, CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id))`
+
+	geometryColumnsSQL = `CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL, CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name), CONSTRAINT uk_gc_table_name UNIQUE (table_name), CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name), CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id))`
+
+	layerSQL = `CREATE TABLE %s (fid INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, geom POLYGON, ref TEXT NOT NULL, "precision" DOUBLE NOT NULL)`
+)
+
+type Options struct {
+	// Extent is the OSGB36 area covered, cells overlapping it are written.
+	Extent nationalgrid.Bounds
+	// Precisions each get a layer named by LayerName.
+	Precisions []float64
+	// Modified is recorded as the layers' last change, now when zero.
+	Modified time.Time
+}
+
+// DefaultOptions writes the 100km squares and 10km sub-squares of the whole grid.
+func DefaultOptions() Options {
+	return Options{
+		Extent: nationalgrid.Bounds{
+			Xmin: 0,
+			Xmax: 7 * nationalgrid.SquareSize,
+			Ymin: 0,
+			Ymax: 13 * nationalgrid.SquareSize,
+		},
+		Precisions: []float64{
+			nationalgrid.SquareSize,
+			nationalgrid.SubSquareSize,
+		},
+	}
+}
+
+// LayerName returns the table name of a precision's layer, e.g. grid_10km.
+func LayerName(precision float64) string {
+	return "grid_" + nationalgrid.FormatPrecision(precision)
+}
+
+// WriteFile writes a GeoPackage to path, replacing any existing file.
+func WriteFile(path string, opts Options) error {
+	err := validateOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+
+	err = writePackage(db, opts)
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	return db.Close()
+}
+
+// Write writes a GeoPackage with a layer per precision, each cell a polygon
+// with ref and precision attributes. SQLite needs a file to write to, so the
+// package is built in a temporary file then copied to w.
+func Write(w io.Writer, opts Options) error {
+	dir, err := os.MkdirTemp("", "gpkg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "grid.gpkg")
+
+	err = WriteFile(path, opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+func validateOptions(opts Options) error {
+	if len(opts.Precisions) == 0 {
+		return fmt.Errorf("at least one precision is required")
+	}
+
+	seen := map[float64]bool{}
+	for _, p := range opts.Precisions {
+		err := nationalgrid.ValidatePrecision(p)
+		if err != nil {
+			return err
+		}
+
+		if seen[p] {
+			return fmt.Errorf("duplicate precision %v", nationalgrid.FormatPrecision(p))
+		}
+		seen[p] = true
+	}
+
+	return nil
+}
+
+// writePackage writes the GeoPackage tables in one transaction.
+func writePackage(db *sql.DB, opts Options) error {
+	modified := opts.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	lastChange := modified.UTC().Format("2006-01-02T15:04:05.000Z")
+
+	// marks the file as a GeoPackage, see the spec's requirements 2 and 3
+	_, err := db.Exec(fmt.Sprintf("PRAGMA application_id = %d; PRAGMA user_version = %d", applicationID, userVersion))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = writeTables(tx, opts, lastChange)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func writeTables(tx *sql.Tx, opts Options, lastChange string) error {
+	for _, stmt := range []string{spatialRefSysSQL, contentsSQL, geometryColumnsSQL} {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range spatialRefSys {
+		_, err := tx.Exec(`INSERT INTO gpkg_spatial_ref_sys VALUES (?, ?, ?, ?, ?, ?)`, row...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range opts.Precisions {
+		name := LayerName(p)
+
+		extent, err := writeLayer(tx, name, p, opts.Extent)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO gpkg_contents (table_name, data_type, identifier, description, last_change, min_x, min_y, max_x, max_y, srs_id) VALUES (?, 'features', ?, ?, ?, ?, ?, ?, ?, ?)`,
+			name, name, "National Grid "+nationalgrid.FormatPrecision(p)+" cells", lastChange,
+			extent.Xmin, extent.Ymin, extent.Xmax, extent.Ymax, nationalgrid.SRID,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO gpkg_geometry_columns VALUES (?, 'geom', 'POLYGON', ?, 0, 0)`, name, nationalgrid.SRID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLayer writes the cells of a precision, returning their extent.
+func writeLayer(tx *sql.Tx, name string, precision float64, extent nationalgrid.Bounds) (nationalgrid.Bounds, error) {
+	layerExtent := nationalgrid.Bounds{
+		Xmin: math.Inf(1),
+		Ymin: math.Inf(1),
+		Xmax: math.Inf(-1),
+		Ymax: math.Inf(-1),
+	}
+
+	refs, err := nationalgrid.GridRefsInBounds(extent, precision)
+	if err != nil {
+		return layerExtent, err
+	}
+
+	quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+
+	_, err = tx.Exec(fmt.Sprintf(layerSQL, quoted))
+	if err != nil {
+		return layerExtent, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO ` + quoted + ` (geom, ref, "precision") VALUES (?, ?, ?)`)
+	if err != nil {
+		return layerExtent, err
+	}
+	defer stmt.Close()
+
+	for _, ref := range refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return layerExtent, err
+		}
+
+		layerExtent.Xmin = math.Min(layerExtent.Xmin, b.Xmin)
+		layerExtent.Ymin = math.Min(layerExtent.Ymin, b.Ymin)
+		layerExtent.Xmax = math.Max(layerExtent.Xmax, b.Xmax)
+		layerExtent.Ymax = math.Max(layerExtent.Ymax, b.Ymax)
+
+		_, err = stmt.Exec(geometryBlob(b), ref.String(), precision)
+		if err != nil {
+			return layerExtent, err
+		}
+	}
+
+	if len(refs) == 0 {
+		layerExtent = nationalgrid.Bounds{}
+	}
+
+	return layerExtent, nil
+}
+
+// geometryBlob returns a GeoPackage binary polygon: a little endian header
+// with the srs id and xy envelope, then the WKB.
+func geometryBlob(b nationalgrid.Bounds) []byte {
+	header := make([]byte, 8+4*8)
+	copy(header, []byte{'G', 'P', 0, 0x03})
+	binary.LittleEndian.PutUint32(header[4:], uint32(nationalgrid.SRID))
+
+	for i, v := range []float64{b.Xmin, b.Xmax, b.Ymin, b.Ymax} {
+		binary.LittleEndian.PutUint64(header[8+8*i:], math.Float64bits(v))
+	}
+
+	return append(header, b.ToWKB()...)
+}
@@ -0,0 +1,204 @@
+package gpkg
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func writeTestPackage(t *testing.T) *sql.DB {
+	t.Helper()
+
+	opts := DefaultOptions()
+	opts.Extent = nationalgrid.Bounds{Xmin: 300000, Xmax: 400000, Ymin: 400000, Ymax: 500000}
+	opts.Precisions = append(opts.Precisions, nationalgrid.KmSquareSize)
+	opts.Modified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	path := filepath.Join(t.TempDir(), "grid.gpkg")
+
+	err := WriteFile(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func queryInt(t *testing.T, db *sql.DB, query string) int64 {
+	t.Helper()
+
+	var v int64
+
+	err := db.QueryRow(query).Scan(&v)
+	if err != nil {
+		t.Fatalf("%v: %v", query, err)
+	}
+
+	return v
+}
+
+func TestWrite(t *testing.T) {
+	db := writeTestPackage(t)
+
+	var check string
+	err := db.QueryRow("PRAGMA integrity_check").Scan(&check)
+	if err != nil || check != "ok" {
+		t.Fatalf("expected an ok integrity check, got %v %v", check, err)
+	}
+
+	if queryInt(t, db, "PRAGMA application_id") != applicationID || queryInt(t, db, "PRAGMA user_version") != userVersion {
+		t.Fatalf("expected GeoPackage application id and user version")
+	}
+
+	expectedCounts := map[string]int64{
+		"gpkg_spatial_ref_sys":  4,
+		"gpkg_contents":         3,
+		"gpkg_geometry_columns": 3,
+		"grid_100km":            1,
+		"grid_10km":             100,
+		"grid_1km":              10000,
+	}
+
+	for name, expected := range expectedCounts {
+		actual := queryInt(t, db, "SELECT count(*) FROM "+name)
+		if expected != actual {
+			t.Fatalf("%v expected %v rows, got %v", name, expected, actual)
+		}
+	}
+
+	var definition string
+	err = db.QueryRow("SELECT definition FROM gpkg_spatial_ref_sys WHERE srs_id = ?", nationalgrid.SRID).Scan(&definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(definition, `AUTHORITY["EPSG","27700"]`) {
+		t.Fatalf("expected the EPSG:27700 definition, got %v", definition)
+	}
+
+	var lastChange string
+	var minX, maxY float64
+	var srsID int64
+	// cast, as the driver reads DATETIME columns as times
+	err = db.QueryRow("SELECT CAST(last_change AS TEXT), min_x, max_y, srs_id FROM gpkg_contents WHERE table_name = 'grid_10km'").Scan(&lastChange, &minX, &maxY, &srsID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lastChange != "2024-01-02T03:04:05.000Z" || minX != 300000 || maxY != 500000 || srsID != nationalgrid.SRID {
+		t.Fatalf("unexpected contents %v %v %v %v", lastChange, minX, maxY, srsID)
+	}
+
+	var geometryType string
+	err = db.QueryRow("SELECT geometry_type_name FROM gpkg_geometry_columns WHERE table_name = 'grid_1km' AND column_name = 'geom'").Scan(&geometryType)
+	if err != nil || geometryType != "POLYGON" {
+		t.Fatalf("expected a POLYGON geometry column, got %v %v", geometryType, err)
+	}
+
+	var ref string
+	err = db.QueryRow("SELECT ref FROM grid_1km WHERE fid = 1").Scan(&ref)
+	if err != nil || ref != "SD0000" {
+		t.Fatalf("expected SD0000, got %v %v", ref, err)
+	}
+
+	var blob []byte
+	var precision float64
+	err = db.QueryRow("SELECT geom, precision FROM grid_10km WHERE ref = 'SD81'").Scan(&blob, &precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if precision != nationalgrid.SubSquareSize {
+		t.Fatalf("expected precision %v, got %v", nationalgrid.SubSquareSize, precision)
+	}
+
+	// GP header, srs id, envelope then the WKB polygon
+	if string(blob[:2]) != "GP" || binary.LittleEndian.Uint32(blob[4:]) != nationalgrid.SRID {
+		t.Fatalf("unexpected geometry header %x", blob[:8])
+	}
+
+	envelope := make([]float64, 4)
+	for i := range envelope {
+		envelope[i] = math.Float64frombits(binary.LittleEndian.Uint64(blob[8+8*i:]))
+	}
+
+	expected := []float64{380000, 390000, 410000, 420000}
+	for i := range expected {
+		if expected[i] != envelope[i] {
+			t.Fatalf("expected envelope %v, got %v", expected, envelope)
+		}
+	}
+
+	b, err := nationalgrid.GridRef{Square: "SD", SubSquare: "81"}.ToWKB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b, blob[40:]) {
+		t.Fatalf("expected wkb %x, got %x", b, blob[40:])
+	}
+}
+
+func TestWriteReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid.gpkg")
+
+	err := os.WriteFile(path, []byte("not a database"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Precisions = []float64{nationalgrid.SquareSize}
+
+	err = WriteFile(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = Write(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(written, []byte("SQLite format 3\x00")) || !bytes.HasPrefix(buf.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Fatalf("expected SQLite files")
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := map[string][]float64{
+		"none":      nil,
+		"invalid":   {2000},
+		"duplicate": {nationalgrid.SubSquareSize, nationalgrid.SubSquareSize},
+	}
+
+	for name, precisions := range tests {
+		opts := DefaultOptions()
+		opts.Precisions = precisions
+
+		err := WriteFile(filepath.Join(t.TempDir(), "grid.gpkg"), opts)
+		if err == nil {
+			t.Fatalf("%v expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package nationalgrid
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+type KMLOptions struct {
+	// Name is the document name shown in Google Earth.
+	Name string
+	// Densify is the number of points inserted along each cell edge so the
+	// curved grid lines are followed once reprojected to WGS84.
+	Densify int
+	// Labels adds a placemark labelled with the grid ref at each cell centre.
+	Labels    bool
+	LineColor color.Color
+	LineWidth float64
+	FillColor color.Color
+}
+
+// DefaultKMLOptions draws labelled black outlines with no fill.
+var DefaultKMLOptions = KMLOptions{
+	Name:      "National Grid",
+	Densify:   10,
+	Labels:    true,
+	LineColor: color.RGBA{0x00, 0x00, 0x00, 0xFF},
+	LineWidth: 1,
+	FillColor: color.RGBA{0x00, 0x00, 0x00, 0x00},
+}
+
+type kmlDocument struct {
+	XMLName xml.Name       `xml:"kml"`
+	XMLNS   string         `xml:"xmlns,attr"`
+	Name    string         `xml:"Document>name"`
+	Styles  []kmlStyle     `xml:"Document>Style"`
+	Marks   []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlStyle struct {
+	ID         string         `xml:"id,attr"`
+	LineStyle  *kmlLineStyle  `xml:"LineStyle,omitempty"`
+	PolyStyle  *kmlPolyStyle  `xml:"PolyStyle,omitempty"`
+	IconStyle  *kmlIconStyle  `xml:"IconStyle,omitempty"`
+	LabelStyle *kmlLabelStyle `xml:"LabelStyle,omitempty"`
+}
+
+type kmlLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlPolyStyle struct {
+	Color string `xml:"color"`
+}
+
+type kmlIconStyle struct {
+	Scale float64 `xml:"scale"`
+}
+
+type kmlLabelStyle struct {
+	Scale float64 `xml:"scale"`
+}
+
+type kmlPlacemark struct {
+	Name     string      `xml:"name"`
+	StyleURL string      `xml:"styleUrl"`
+	Polygon  *kmlPolygon `xml:"Polygon,omitempty"`
+	Point    *kmlPoint   `xml:"Point,omitempty"`
+}
+
+type kmlPolygon struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes a KML document with a polygon per grid ref, reprojected to WGS84.
+func WriteKML(w io.Writer, refs []GridRef, opts KMLOptions) error {
+	doc := kmlDocument{
+		XMLNS: kmlNamespace,
+		Name:  opts.Name,
+		Styles: []kmlStyle{
+			{
+				ID: "cell",
+				LineStyle: &kmlLineStyle{
+					Color: kmlColor(opts.LineColor),
+					Width: opts.LineWidth,
+				},
+				PolyStyle: &kmlPolyStyle{
+					Color: kmlColor(opts.FillColor),
+				},
+			},
+			{
+				ID: "label",
+				IconStyle: &kmlIconStyle{
+					Scale: 0,
+				},
+				LabelStyle: &kmlLabelStyle{
+					Scale: 1,
+				},
+			},
+		},
+	}
+
+	for _, ref := range refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return err
+		}
+
+		ring := b.DensifiedRing(opts.Densify)
+		coords := make([]string, 0, len(ring))
+		for _, p := range ring {
+			coords = append(coords, kmlCoord(p[0], p[1]))
+		}
+
+		doc.Marks = append(doc.Marks, kmlPlacemark{
+			Name:     ref.String(),
+			StyleURL: "#cell",
+			Polygon: &kmlPolygon{
+				Tessellate:  1,
+				Coordinates: strings.Join(coords, " "),
+			},
+		})
+
+		if opts.Labels {
+			east, north, err := getGridCoordCenter(ref)
+			if err != nil {
+				return err
+			}
+
+			doc.Marks = append(doc.Marks, kmlPlacemark{
+				Name:     ref.String(),
+				StyleURL: "#label",
+				Point: &kmlPoint{
+					Coordinates: kmlCoord(east, north),
+				},
+			})
+		}
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
+
+// WriteKMZ writes the KML document zipped as doc.kml.
+func WriteKMZ(w io.Writer, refs []GridRef, opts KMLOptions) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create("doc.kml")
+	if err != nil {
+		return err
+	}
+
+	err = WriteKML(f, refs, opts)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// kmlCoord reprojects an OSGB36 easting / northing to a KML lon,lat tuple.
+func kmlCoord(east, north float64) string {
+	lon, lat := osgb36ToWGS84(east, north)
+
+	return strconv.FormatFloat(lon, 'f', 7, 64) + "," + strconv.FormatFloat(lat, 'f', 7, 64)
+}
+
+// kmlColor formats a colour as KML aabbggrr hex.
+func kmlColor(c color.Color) string {
+	if c == nil {
+		return "00000000"
+	}
+
+	nc, _ := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	return fmt.Sprintf("%02x%02x%02x%02x", nc.A, nc.B, nc.G, nc.R)
+}
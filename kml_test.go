@@ -0,0 +1,140 @@
+package nationalgrid
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteKML(t *testing.T) {
+	refs := []GridRef{
+		{Square: "SD"},
+		{Square: "SD", SubSquare: "91"},
+	}
+
+	opts := DefaultKMLOptions
+	opts.Densify = 3
+	opts.LineColor = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+
+	var buf bytes.Buffer
+	err := WriteKML(&buf, refs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlDocument
+	err = xml.Unmarshal(buf.Bytes(), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a cell and a label per ref
+	if len(doc.Marks) != 4 {
+		t.Fatalf("expected %v placemarks, got %v", 4, len(doc.Marks))
+	}
+
+	if doc.Styles[0].LineStyle.Color != "ff0000ff" {
+		t.Fatalf("expected line colour %v, got %v", "ff0000ff", doc.Styles[0].LineStyle.Color)
+	}
+
+	cell := doc.Marks[0]
+	if cell.Name != "SD" || cell.Polygon == nil {
+		t.Fatalf("unexpected placemark %+v", cell)
+	}
+
+	// 4 edges of 3 inserted points plus the corners and the closing point
+	coords := strings.Fields(cell.Polygon.Coordinates)
+	if len(coords) != 4*4+1 {
+		t.Fatalf("expected %v coordinates, got %v", 4*4+1, len(coords))
+	}
+
+	if coords[0] != coords[len(coords)-1] {
+		t.Fatalf("expected closed ring, got %v and %v", coords[0], coords[len(coords)-1])
+	}
+
+	label := doc.Marks[3]
+	if label.Name != "SD91" || label.Point == nil {
+		t.Fatalf("unexpected label %+v", label)
+	}
+}
+
+func TestWriteKMLNoLabels(t *testing.T) {
+	opts := DefaultKMLOptions
+	opts.Labels = false
+
+	var buf bytes.Buffer
+	err := WriteKML(&buf, NationalGridSquareRefs(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlDocument
+	err = xml.Unmarshal(buf.Bytes(), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Marks) != len(NationalGridSquares) {
+		t.Fatalf("expected %v placemarks, got %v", len(NationalGridSquares), len(doc.Marks))
+	}
+}
+
+func TestWriteKMZ(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteKMZ(&buf, []GridRef{{Square: "SV"}}, DefaultKMLOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zr.File) != 1 || zr.File[0].Name != "doc.kml" {
+		t.Fatalf("unexpected kmz contents %+v", zr.File)
+	}
+
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "<name>SV</name>") {
+		t.Fatalf("expected SV placemark in %v", string(data))
+	}
+}
+
+func TestBoundsDensifiedRing(t *testing.T) {
+	b := Bounds{Xmin: 0, Xmax: 10, Ymin: 0, Ymax: 10}
+
+	tests := map[int]int{
+		-5: 5,
+		-1: 5,
+		0:  5,
+		1:  9,
+		4:  21,
+	}
+
+	for n, expected := range tests {
+		ring := b.DensifiedRing(n)
+
+		if len(ring) != expected {
+			t.Fatalf("%v expected %v points, got %v", n, expected, len(ring))
+		}
+
+		if ring[0] != ring[len(ring)-1] {
+			t.Fatalf("%v expected a closed ring, got %+v", n, ring)
+		}
+	}
+}
@@ -19,8 +19,8 @@ func (l LocationType) String() string {
 }
 
 type LatLon struct {
-	Lat float64
-	Lon float64
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
 }
 
 type Location struct {
@@ -69,3 +69,61 @@ func (c Location) ToOSGB36() OSGB36LatLon {
 
 	return r
 }
+
+func (c Location) ToWGS84() LatLon {
+	var r LatLon
+
+	switch c.Type {
+	case WGS84.String():
+		r = c.LatLon
+	case OSGB36.String():
+		lon, lat := osgb36ToWGS84(c.LatLon.Lat, c.LatLon.Lon)
+		r = LatLon{
+			Lat: lat,
+			Lon: lon,
+		}
+	case NATIONALGRID.String():
+		east, north, _ := GetGridLatLon(c.GridRef)
+		lon, lat := osgb36ToWGS84(east, north)
+		r = LatLon{
+			Lat: lat,
+			Lon: lon,
+		}
+	}
+
+	return r
+}
+
+// osgb36ToWGS84 projects a National Grid easting / northing to WGS84 lon / lat.
+func osgb36ToWGS84(east, north float64) (float64, float64) {
+	lon, lat, _ := wgs84.OSGB36NationalGrid().To(wgs84.LonLat())(east, north, 0)
+
+	return lon, lat
+}
+
+// wgs84ToOSGB36 projects a WGS84 lon / lat to a National Grid easting / northing.
+func wgs84ToOSGB36(lon, lat float64) (float64, float64) {
+	east, north, _ := wgs84.LonLat().To(wgs84.OSGB36NationalGrid())(lon, lat, 0)
+
+	return east, north
+}
+
+// ToWGS84 projects the coordinate to a WGS84 lat / lon.
+func (e EastingNorthing) ToWGS84() LatLon {
+	lon, lat := osgb36ToWGS84(e.Easting, e.Northing)
+
+	return LatLon{
+		Lat: lat,
+		Lon: lon,
+	}
+}
+
+// ToEastingNorthing projects a WGS84 lat / lon to the National Grid.
+func (l LatLon) ToEastingNorthing() EastingNorthing {
+	east, north := wgs84ToOSGB36(l.Lon, l.Lat)
+
+	return EastingNorthing{
+		Easting:  east,
+		Northing: north,
+	}
+}
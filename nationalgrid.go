@@ -2,11 +2,11 @@ package nationalgrid
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/rockwell-uk/go-geos-draw/geom"
 	geos "github.com/twpayne/go-geos"
 )
 
@@ -15,49 +15,23 @@ var (
 	subSquareCoords = make(map[string]map[int]*geos.Bounds)
 )
 
+// The squares and subsquares are plain bounds built without a geos context, so
+// they can be read from any goroutine.
 func init() {
-	// We need a context here
-	gctx := geos.NewContext()
-
 	for key, gridSquare := range NationalGridSquares {
 		tileX := gridSquare[0] * SquareSize
 		tileY := gridSquare[1] * SquareSize
 
-		boundingBox := Bounds{
-			Xmin: tileX,
-			Xmax: tileX + SquareSize,
-			Ymin: tileY,
-			Ymax: tileY + SquareSize,
-		}
-
-		gridSquarePoly := boundingBox.ToPolygon()
-		gridSquareGeom, _ := gctx.NewGeomFromWKT(gridSquarePoly)
-
-		gridSquares[key] = gridSquareGeom.Bounds()
+		gridSquares[key] = geos.NewBounds(tileX, tileY, tileX+SquareSize, tileY+SquareSize)
 
 		subSquareCoords[key] = make(map[int]*geos.Bounds)
-	}
 
-	for key, gridSquare := range gridSquares {
 		for i := 0; i <= 99; i++ {
-			bits := fmt.Sprintf("%02d", i)
-			addX, _ := strconv.Atoi(string(bits[0]))
-			addY, _ := strconv.Atoi(string(bits[1]))
-
-			xAdj := gridSquare.MinX + (float64(addX) * SubSquareSize)
-			yAdj := gridSquare.MinY + (float64(addY) * SubSquareSize)
-
-			boundingBox := Bounds{
-				Xmin: xAdj,
-				Xmax: xAdj + SubSquareSize,
-				Ymin: yAdj,
-				Ymax: yAdj + SubSquareSize,
-			}
-
-			subSquarePolygon := boundingBox.ToPolygon()
-			subSquareGeom, _ := gctx.NewGeomFromWKT(subSquarePolygon)
+			// the tens digit is the easting, the units the northing
+			xAdj := tileX + float64(i/10)*SubSquareSize
+			yAdj := tileY + float64(i%10)*SubSquareSize
 
-			subSquareCoords[key][i] = subSquareGeom.Bounds()
+			subSquareCoords[key][i] = geos.NewBounds(xAdj, yAdj, xAdj+SubSquareSize, yAdj+SubSquareSize)
 		}
 	}
 }
@@ -82,6 +56,43 @@ func GetSubSquares(g *geos.Bounds) map[string][]int {
 	return subSquares
 }
 
+// mergeSubSquares adds the subsquares in src to dst, keeping them sorted and unique.
+func mergeSubSquares(dst, src map[string][]int) {
+	for k, subSquares := range src {
+		seen := make(map[int]bool, len(dst[k]))
+		for _, i := range dst[k] {
+			seen[i] = true
+		}
+
+		for _, i := range subSquares {
+			if !seen[i] {
+				seen[i] = true
+				dst[k] = append(dst[k], i)
+			}
+		}
+
+		sort.Ints(dst[k])
+	}
+}
+
+// NationalGridSquareRefs returns the 100km squares sorted alphabetically.
+func NationalGridSquareRefs() []GridRef {
+	keys := make([]string, 0, len(NationalGridSquares))
+	for ref := range NationalGridSquares {
+		keys = append(keys, ref)
+	}
+	sort.Strings(keys)
+
+	refs := make([]GridRef, 0, len(keys))
+	for _, key := range keys {
+		refs = append(refs, GridRef{
+			Square: key,
+		})
+	}
+
+	return refs
+}
+
 func intersects(item *geos.Bounds, target *geos.Bounds) bool {
 	return item.Intersects(target)
 }
@@ -98,100 +109,273 @@ func GetGridLatLon(ref string) (float64, float64, error) {
 }
 
 func getGridCoordCenter(gridRef GridRef) (float64, float64, error) {
-	var x, y float64
-	var g *geos.Geom
-	var gridCoords []float64
-	var err error
+	b, err := gridRef.Bounds()
+	if err != nil {
+		return 0, 0, err
+	}
 
-	var squareBlx, squareBly float64
-	var subSquareBlx, subSquareBly float64
-	var quadrantBlx, quadrantBly float64
+	return b.Xmin + (b.Xmax-b.Xmin)/2, b.Ymin + (b.Ymax-b.Ymin)/2, nil
+}
 
-	var targetBlx, targetBly float64
-	var targetSize float64
+// Precision returns the width of the grid cell referenced, in metres.
+func (g GridRef) Precision() float64 {
+	size := SquareSize / math.Pow(10, float64(len(g.SubSquare)/2))
 
-	gridCoords = NationalGridSquares[gridRef.Square]
+	if g.Quadrant != "" {
+		return size / 2
+	}
 
-	squareBlx = gridCoords[0] * SquareSize
-	squareBly = gridCoords[1] * SquareSize
+	return size
+}
 
-	switch {
-	case gridRef.Quadrant != "":
-		subSquareX, _ := strconv.Atoi(string(gridRef.SubSquare[0]))
-		subSquareY, _ := strconv.Atoi(string(gridRef.SubSquare[1]))
+// Bounds returns the OSGB36 extent of the grid cell referenced.
+func (g GridRef) Bounds() (Bounds, error) {
+	var b Bounds
 
-		subSquareBlx = squareBlx + (float64(subSquareX) * SubSquareSize)
-		subSquareBly = squareBly + (float64(subSquareY) * SubSquareSize)
+	gridCoords, ok := NationalGridSquares[g.Square]
+	if !ok {
+		return b, fmt.Errorf("unable to load sector %v", g.Square)
+	}
 
-		switch gridRef.Quadrant {
-		case SW:
-			quadrantBlx = subSquareBlx
-			quadrantBly = subSquareBly
+	xmin := gridCoords[0] * SquareSize
+	ymin := gridCoords[1] * SquareSize
 
-		case NW:
-			quadrantBlx = subSquareBlx
-			quadrantBly = subSquareBly + QuadrantSize
+	if g.SubSquare != "" {
+		digits := len(g.SubSquare) / 2
+		if len(g.SubSquare)%2 != 0 || digits > maxDigits/2 {
+			return b, fmt.Errorf("invalid subsquare %v", g.SubSquare)
+		}
+
+		subSquareX, err := strconv.Atoi(g.SubSquare[:digits])
+		if err != nil {
+			return b, fmt.Errorf("invalid subsquare %v", g.SubSquare)
+		}
+		subSquareY, err := strconv.Atoi(g.SubSquare[digits:])
+		if err != nil {
+			return b, fmt.Errorf("invalid subsquare %v", g.SubSquare)
+		}
+
+		size := SquareSize / math.Pow(10, float64(digits))
+
+		xmin += float64(subSquareX) * size
+		ymin += float64(subSquareY) * size
+	}
+
+	quadrantSize := g.Precision()
+
+	switch g.Quadrant {
+	case "", SW:
+	case NW:
+		ymin += quadrantSize
+	case SE:
+		xmin += quadrantSize
+	case NE:
+		xmin += quadrantSize
+		ymin += quadrantSize
+	default:
+		return b, fmt.Errorf("invalid quadrant %v", g.Quadrant)
+	}
+
+	size := g.Precision()
+
+	return Bounds{
+		Xmin: xmin,
+		Xmax: xmin + size,
+		Ymin: ymin,
+		Ymax: ymin + size,
+	}, nil
+}
+
+// GetGridRef returns the grid ref of the cell containing an OSGB36 easting /
+// northing at one of the Precisions or QuadrantPrecisions.
+func GetGridRef(east, north float64, precision float64) (GridRef, error) {
+	var g GridRef
 
-		case SE:
-			quadrantBlx = subSquareBlx + QuadrantSize
-			quadrantBly = subSquareBly
+	err := ValidatePrecision(precision)
+	if err != nil {
+		return g, err
+	}
+
+	squareX := math.Floor(east / SquareSize)
+	squareY := math.Floor(north / SquareSize)
 
-		case NE:
-			quadrantBlx = subSquareBlx + QuadrantSize
-			quadrantBly = subSquareBly + QuadrantSize
+	for key, gridSquare := range NationalGridSquares {
+		if gridSquare[0] == squareX && gridSquare[1] == squareY {
+			g.Square = key
+			break
 		}
+	}
+
+	if g.Square == "" {
+		return g, fmt.Errorf("%v, %v is outside the national grid", east, north)
+	}
+
+	size := precision
+	if isQuadrantPrecision(precision) {
+		size = precision * 2
+	}
+
+	if size != SquareSize {
+		xOffset := east - squareX*SquareSize
+		yOffset := north - squareY*SquareSize
+
+		digits := int(math.Round(math.Log10(SquareSize / size)))
+
+		subSquareX := math.Floor(xOffset / size)
+		subSquareY := math.Floor(yOffset / size)
+
+		g.SubSquare = fmt.Sprintf("%0*d%0*d", digits, int(subSquareX), digits, int(subSquareY))
+	}
+
+	if size == precision {
+		return g, nil
+	}
 
-		targetBlx = quadrantBlx
-		targetBly = quadrantBly
-		targetSize = QuadrantSize
+	b, err := g.Bounds()
+	if err != nil {
+		return g, err
+	}
 
-	case gridRef.SubSquare != "":
-		subSquareX, _ := strconv.Atoi(string(gridRef.SubSquare[0]))
-		subSquareY, _ := strconv.Atoi(string(gridRef.SubSquare[1]))
+	g.Quadrant = quadrantAt(b, east, north)
 
-		subSquareBlx = squareBlx + (float64(subSquareX) * SubSquareSize)
-		subSquareBly = squareBly + (float64(subSquareY) * SubSquareSize)
+	return g, nil
+}
 
-		targetBlx = subSquareBlx
-		targetBly = subSquareBly
-		targetSize = SubSquareSize
+// MaxGridRefs is the most cells GridRefsInBounds will list, enough for every
+// 1km square of the grid.
+const MaxGridRefs = 1000000
 
-	case gridRef.Square != "":
-		targetBlx = squareBlx
-		targetBly = squareBly
-		targetSize = SquareSize
+// GridRefsInBounds returns the cells at the given precision overlapping b,
+// ordered south to north then west to east, omitting any outside the grid.
+// It fails rather than list more than MaxGridRefs cells.
+func GridRefsInBounds(b Bounds, precision float64) ([]GridRef, error) {
+	err := ValidatePrecision(precision)
+	if err != nil {
+		return nil, err
 	}
 
-	g, err = gridCoordsToGeom(
-		[]float64{
-			targetBlx,
-			targetBly,
-		},
-		targetSize,
-	)
+	if b.Xmin > b.Xmax || b.Ymin > b.Ymax {
+		return nil, fmt.Errorf("invalid bounds %+v", b)
+	}
+
+	x0, x1 := cellRange(b.Xmin, b.Xmax, precision)
+	y0, y1 := cellRange(b.Ymin, b.Ymax, precision)
+
+	cells := (x1 - x0 + 1) * (y1 - y0 + 1)
+	if cells > MaxGridRefs {
+		return nil, fmt.Errorf("%v cells of %v exceed the maximum of %v", cells, FormatPrecision(precision), MaxGridRefs)
+	}
+
+	var refs []GridRef
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			g, err := GetGridRef((x+0.5)*precision, (y+0.5)*precision, precision)
+			if err != nil {
+				continue
+			}
+			refs = append(refs, g)
+		}
+	}
+
+	return refs, nil
+}
+
+// cellRange returns the first and last cell index overlapping lo to hi,
+// cells only touching at an edge are excluded unless the range is empty.
+func cellRange(lo, hi, size float64) (float64, float64) {
+	first := math.Floor(lo / size)
+	last := math.Ceil(hi/size) - 1
+
+	if last < first {
+		last = first
+	}
+
+	return first, last
+}
+
+// Neighbours returns the surrounding cells at the same precision, clockwise
+// from north, omitting any that fall outside the national grid.
+func (g GridRef) Neighbours() ([]GridRef, error) {
+	b, err := g.Bounds()
 	if err != nil {
-		return x, y, err
+		return nil, err
 	}
 
-	center := geom.CenterFromGeometry(g)
+	size := g.Precision()
+	x := b.Xmin + size/2
+	y := b.Ymin + size/2
 
-	return center[0], center[1], nil
+	offsets := [][2]float64{
+		{0, 1}, {1, 1}, {1, 0}, {1, -1},
+		{0, -1}, {-1, -1}, {-1, 0}, {-1, 1},
+	}
+
+	neighbours := make([]GridRef, 0, len(offsets))
+
+	for _, o := range offsets {
+		n, err := GetGridRef(x+o[0]*size, y+o[1]*size, size)
+		if err != nil {
+			continue
+		}
+		neighbours = append(neighbours, n)
+	}
+
+	return neighbours, nil
+}
+
+// ValidatePrecision checks a cell size is one of the Precisions or
+// QuadrantPrecisions.
+func ValidatePrecision(precision float64) error {
+	for _, p := range Precisions {
+		if precision == p {
+			return nil
+		}
+	}
+
+	if isQuadrantPrecision(precision) {
+		return nil
+	}
+
+	return fmt.Errorf("unsupported precision %v", precision)
 }
 
-func gridCoordsToGeom(bl []float64, tileSize float64) (*geos.Geom, error) {
-	var g *geos.Geom
+// ParsePrecision parses a precision such as "10km", "100m" or "5000" into metres.
+func ParsePrecision(s string) (float64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	unit := 1.0
 
-	xmin := bl[0]
-	ymin := bl[1]
-	xmax := xmin + tileSize
-	ymax := ymin + tileSize
+	switch {
+	case strings.HasSuffix(v, "km"):
+		v = strings.TrimSuffix(v, "km")
+		unit = 1000
+	case strings.HasSuffix(v, "m"):
+		v = strings.TrimSuffix(v, "m")
+	}
 
-	bounds, err := geom.BoundsGeom(xmin, xmax, ymin, ymax)
+	p, err := strconv.ParseFloat(v, 64)
 	if err != nil {
-		return g, err
+		return 0, fmt.Errorf("unsupported precision %v", s)
+	}
+
+	p *= unit
+
+	err = ValidatePrecision(p)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported precision %v", s)
 	}
 
-	return bounds, nil
+	return p, nil
+}
+
+// FormatPrecision formats a precision in metres as ParsePrecision accepts it,
+// e.g. "10km" or "100m".
+func FormatPrecision(p float64) string {
+	if p >= 1000 {
+		return strconv.FormatFloat(p/1000, 'f', -1, 64) + "km"
+	}
+
+	return strconv.FormatFloat(p, 'f', -1, 64) + "m"
 }
 
 func ParseGridRef(ref string) (GridRef, error) {
@@ -204,34 +388,18 @@ func ParseGridRef(ref string) (GridRef, error) {
 
 	l := len(ref)
 
-	switch l {
-	case 2:
-		return GridRef{
-			Square: ref,
-		}, nil
-
-	case 4:
-		square := ref[0:2]
-		subsquare := ref[2:4]
-
-		return GridRef{
-			Square:    square,
-			SubSquare: subsquare,
-		}, nil
-
-	case 6:
-		square := ref[0:2]
-		subsquare := ref[2:4]
-		quadrant := ref[4:6]
-
+	if hasQuadrant(ref) {
 		return GridRef{
-			Square:    square,
-			SubSquare: subsquare,
-			Quadrant:  Quadrant(quadrant),
+			Square:    ref[0:2],
+			SubSquare: ref[2 : l-2],
+			Quadrant:  Quadrant(ref[l-2:]),
 		}, nil
 	}
 
-	return g, fmt.Errorf("failed to parse %v", ref)
+	return GridRef{
+		Square:    ref[0:2],
+		SubSquare: ref[2:],
+	}, nil
 }
 
 func DoOverlap(tl1, br1, tl2, br2 []float64) bool {
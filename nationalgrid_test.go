@@ -2,43 +2,17 @@ package nationalgrid
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
+	"math"
 	"os"
-	"path"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"testing"
 
-	"github.com/llgcode/draw2d"
-	"github.com/llgcode/draw2d/draw2dimg"
-	"github.com/rockwell-uk/go-geos-draw/geom"
-	"github.com/rockwell-uk/go-text/fonts"
 	geos "github.com/twpayne/go-geos"
 )
 
-var (
-	gctx     = geos.NewContext()
-	fontData = draw2d.FontData{
-		Name:   "bold",
-		Family: draw2d.FontFamilySans,
-		Style:  draw2d.FontStyleNormal,
-	}
-	textRotation = 0.0
-	black        = color.RGBA{0x00, 0x00, 0x00, 0xFF}
-	white        = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
-	scale        = func(x, y float64) (float64, float64) {
-		return x, y
-	}
-	fillColor   = white
-	strokeWidth = 0.0
-	strokeColor = black
-	lineWidth   = 1.0
-)
-
 func TestLogSquareCentres(t *testing.T) {
 	outFileName := "test-output/national-grid-squares.txt"
 	os.Remove(outFileName)
@@ -152,6 +126,40 @@ func TestParseGridRef(t *testing.T) {
 				Quadrant:  Quadrant("NW"),
 			},
 		},
+		"SD8710": {
+			Ref: GridRef{
+				Square:    "SD",
+				SubSquare: "8710",
+			},
+		},
+		"SD8722110715": {
+			Ref: GridRef{
+				Square:    "SD",
+				SubSquare: "8722110715",
+			},
+		},
+		"SD871": {
+			Fail: true,
+		},
+		"SD8710NE": {
+			Ref: GridRef{
+				Square:    "SD",
+				SubSquare: "8710",
+				Quadrant:  Quadrant("NE"),
+			},
+		},
+		"SDSW": {
+			Ref: GridRef{
+				Square:   "SD",
+				Quadrant: Quadrant("SW"),
+			},
+		},
+		"SD8722110715NE": {
+			Fail: true,
+		},
+		"SD872211071500": {
+			Fail: true,
+		},
 		"SD00XX": {
 			Fail: true,
 		},
@@ -310,29 +318,26 @@ func TestGetGridCoordCenter(t *testing.T) {
 	}
 }
 
-func TestGridCoordsToGeom(t *testing.T) {
-	sdwkt := "POLYGON ((300000.0000000000000000 400000.0000000000000000, 400000.0000000000000000 400000.0000000000000000, 400000.0000000000000000 500000.0000000000000000, 300000.0000000000000000 500000.0000000000000000, 300000.0000000000000000 400000.0000000000000000))"
-
-	expected, err := gctx.NewGeomFromWKT(sdwkt)
-	if err != nil {
-		t.Fatal(err)
+func TestSquareBounds(t *testing.T) {
+	expected := Bounds{
+		Xmin: 300000,
+		Xmax: 400000,
+		Ymin: 400000,
+		Ymax: 500000,
 	}
 
-	gridRef := "SD"
-	err = ValidateSquare(gridRef)
+	gridRef, err := ParseGridRef("SD")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	gridCoords := NationalGridSquares[gridRef]
-
-	g, err := gridCoordsToGeom(gridCoords, SquareSize)
+	actual, err := gridRef.Bounds()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if !reflect.DeepEqual(expected, g) {
-		t.Fatalf("expected %+v, got %+v", expected, g)
+	if expected != actual {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
 	}
 }
 
@@ -344,10 +349,9 @@ func TestGetSubSquares(t *testing.T) {
 		},
 	}
 
-	targetTilePoly := "POLYGON ((387221.1985319799860008 410715.0784210899728350, 392221.1985319799860008 410715.0784210899728350, 392221.1985319799860008 415715.0784210899728350, 387221.1985319799860008 415715.0784210899728350, 387221.1985319799860008 410715.0784210899728350))"
-	targetTileGeom, _ := gctx.NewGeomFromWKT(targetTilePoly)
+	target := geos.NewBounds(387221.1985319799860008, 410715.0784210899728350, 392221.1985319799860008, 415715.0784210899728350)
 
-	actual := GetSubSquares(targetTileGeom.Bounds())
+	actual := GetSubSquares(target)
 
 	if !reflect.DeepEqual(expected, actual) {
 		t.Fatalf("expected %+v, got %+v", expected, actual)
@@ -386,258 +390,307 @@ func TestGetAllFullSquareCoords(t *testing.T) {
 	}
 }
 
-func TestDrawSquares(t *testing.T) {
-	imgWidth := 700
-	imgHeight := 1300
-
-	m, gc := setupImage(imgWidth, imgHeight)
-
-	fontSize := 10.0
-	typeFace := getTypeFace(gc, fontSize)
-	fonts.SetFont(gc, typeFace)
-
-	tileSize := SquareSize / 1000
-
-	for label, square := range NationalGridSquares {
-		tileX := square[0] * tileSize
-		tileY := square[1] * tileSize
-
-		xPos := tileX
-		yPos := float64(imgHeight) - tileY - tileSize
+func TestDoOverlap(t *testing.T) {
+	tests := []struct {
+		tl1      []float64
+		br1      []float64
+		tl2      []float64
+		br2      []float64
+		overlaps bool
+	}{
+		{
+			[]float64{0, 2},
+			[]float64{1, 1},
+			[]float64{1, 1},
+			[]float64{0, 2},
+			false,
+		},
+		{
+			[]float64{0, 3},
+			[]float64{2, 1},
+			[]float64{1, 2},
+			[]float64{3, 0},
+			true,
+		},
+	}
 
-		g, err := geom.BoundsGeom(
-			xPos,
-			xPos+tileSize,
-			yPos,
-			yPos+tileSize,
-		)
-		if err != nil {
-			t.Fatal(err)
-		}
+	for _, tt := range tests {
+		doOverlap := DoOverlap(tt.tl1, tt.br1, tt.tl2, tt.br2)
 
-		l, err := geom.ToLineString(g)
-		if err != nil {
-			t.Fatal(err)
+		if doOverlap != tt.overlaps {
+			t.Fatalf("expected %+v, got %+v", tt.overlaps, doOverlap)
 		}
+	}
+}
 
-		err = geom.DrawLine(gc, l, lineWidth, fillColor, strokeWidth, strokeColor, scale)
-		if err != nil {
-			t.Fatal(err)
-		}
+func TestGetGridRef(t *testing.T) {
+	tests := map[string]struct {
+		East      float64
+		North     float64
+		Precision float64
+		Expected  string
+		Fail      bool
+	}{
+		"square": {
+			East:      387221,
+			North:     410715,
+			Precision: SquareSize,
+			Expected:  "SD",
+		},
+		"subsquare": {
+			East:      387221,
+			North:     410715,
+			Precision: SubSquareSize,
+			Expected:  "SD81",
+		},
+		"quadrant": {
+			East:      392500,
+			North:     417500,
+			Precision: QuadrantSize,
+			Expected:  "SD91NW",
+		},
+		"origin": {
+			East:      0,
+			North:     0,
+			Precision: QuadrantSize,
+			Expected:  "SV00SW",
+		},
+		"outside": {
+			East:      -1,
+			North:     0,
+			Precision: SquareSize,
+			Fail:      true,
+		},
+		"km": {
+			East:      387221,
+			North:     410715,
+			Precision: KmSquareSize,
+			Expected:  "SD8710",
+		},
+		"hectare": {
+			East:      387221,
+			North:     410715,
+			Precision: HectareSize,
+			Expected:  "SD872107",
+		},
+		"metre": {
+			East:      387221,
+			North:     410715,
+			Precision: MetreSize,
+			Expected:  "SD8722110715",
+		},
+		"half square": {
+			East:      387221,
+			North:     410715,
+			Precision: SquareSize / 2,
+			Expected:  "SDSE",
+		},
+		"half km": {
+			East:      387221,
+			North:     410715,
+			Precision: KmSquareSize / 2,
+			Expected:  "SD8710NW",
+		},
+		"half ten metre": {
+			East:      387221,
+			North:     410715,
+			Precision: TenMetreSize / 2,
+			Expected:  "SD87221071NW",
+		},
+		"precision": {
+			East:      387221,
+			North:     410715,
+			Precision: 2000,
+			Fail:      true,
+		},
+	}
 
-		textWidth := fonts.GetTextWidth(typeFace, label)
+	for name, tt := range tests {
+		actual, err := GetGridRef(tt.East, tt.North, tt.Precision)
 
-		labelPos := []float64{
-			xPos + (SquareSize * 5 / 10000) - textWidth/2,
-			yPos + (SquareSize * 5 / 10000),
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", name)
+			}
+			continue
 		}
 
-		err = geom.DrawString(gc, labelPos, textRotation, label)
 		if err != nil {
 			t.Fatal(err)
 		}
-	}
 
-	// draw the image
-	err := savePNG("test-output/all.png", m)
-	if err != nil {
-		t.Fatal(err)
+		if tt.Expected != actual.String() {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, actual)
+		}
 	}
 }
 
-func TestDrawSubSectors(t *testing.T) {
-	imgWidth := 1000
-	imgHeight := 1000
-
-	sectorName := "SV"
-	if _, ok := NationalGridSquares[sectorName]; !ok {
-		t.Fatalf("Unable to load sector %v", sectorName)
+func TestLocationToWGS84(t *testing.T) {
+	l := Location{
+		Type:    NATIONALGRID.String(),
+		GridRef: "SD91NW",
 	}
 
-	m, gc := setupImage(imgWidth, imgHeight)
-
-	fontSize := 30.0
-	typeFace := getTypeFace(gc, fontSize)
-	fonts.SetFont(gc, typeFace)
-	fm := fonts.GetFaceMetrics(typeFace)
-	textWidth := fonts.GetTextWidth(typeFace, sectorName)
+	latLon := l.ToWGS84()
 
-	tileSize := SquareSize / 100
+	roundTrip := Location{
+		Type:   WGS84.String(),
+		LatLon: latLon,
+	}.ToOSGB36()
 
-	if _, ok := NationalGridSquares[sectorName]; !ok {
-		t.Fatalf("Unable to load sector %v", sectorName)
+	if math.Abs(roundTrip.LatLon.Lat-392500) > 1 || math.Abs(roundTrip.LatLon.Lon-417500) > 1 {
+		t.Fatalf("expected %v, %v, got %+v", 392500, 417500, roundTrip)
 	}
-	sector := NationalGridSquares[sectorName]
+}
 
-	tileX := sector[0] * SquareSize
-	tileY := sector[1] * SquareSize
+func TestGridRefsInBounds(t *testing.T) {
+	tests := map[string]struct {
+		Bounds    Bounds
+		Precision float64
+		Expected  []string
+	}{
+		"subsquares": {
+			Bounds: Bounds{
+				Xmin: 387221,
+				Xmax: 392221,
+				Ymin: 410715,
+				Ymax: 415715,
+			},
+			Precision: SubSquareSize,
+			Expected:  []string{"SD81", "SD91"},
+		},
+		"aligned": {
+			Bounds: Bounds{
+				Xmin: 390000,
+				Xmax: 400000,
+				Ymin: 410000,
+				Ymax: 420000,
+			},
+			Precision: QuadrantSize,
+			Expected:  []string{"SD91SW", "SD91SE", "SD91NW", "SD91NE"},
+		},
+		"point": {
+			Bounds: Bounds{
+				Xmin: 392500,
+				Xmax: 392500,
+				Ymin: 417500,
+				Ymax: 417500,
+			},
+			Precision: SquareSize,
+			Expected:  []string{"SD"},
+		},
+		"outside": {
+			Bounds: Bounds{
+				Xmin: -100000,
+				Xmax: 100000,
+				Ymin: 0,
+				Ymax: 50000,
+			},
+			Precision: SquareSize,
+			Expected:  []string{"SV"},
+		},
+	}
 
-	tileX /= 1000
-	tileY /= 1000
+	for name, tt := range tests {
+		refs, err := GridRefsInBounds(tt.Bounds, tt.Precision)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	xPos := tileX
-	yPos := float64(imgHeight) - tileY - tileSize
+		actual := make([]string, 0, len(refs))
+		for _, r := range refs {
+			actual = append(actual, r.String())
+		}
 
-	g, err := geom.BoundsGeom(
-		xPos,
-		xPos+tileSize,
-		yPos,
-		yPos+tileSize,
-	)
-	if err != nil {
-		t.Fatal(err)
+		if !reflect.DeepEqual(tt.Expected, actual) {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, actual)
+		}
 	}
+}
 
-	l, err := geom.ToLineString(g)
-	if err != nil {
-		t.Fatal(err)
-	}
+func TestGridRefsInBoundsLimit(t *testing.T) {
+	grid := Bounds{Xmin: 0, Xmax: 700000, Ymin: 0, Ymax: 1300000}
 
-	err = geom.DrawLine(gc, l, lineWidth, fillColor, strokeWidth, strokeColor, scale)
+	refs, err := GridRefsInBounds(grid, KmSquareSize)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	labelPos := []float64{
-		xPos + (SquareSize * 5 / 1000) - textWidth/2,
-		yPos + (SquareSize * 5 / 1000) + ((fm.Ascent - fm.Descent) / 2),
+	if len(refs) == 0 || len(refs) > MaxGridRefs {
+		t.Fatalf("expected up to %v refs, got %v", MaxGridRefs, len(refs))
 	}
 
-	err = geom.DrawString(gc, labelPos, textRotation, sectorName)
-	if err != nil {
-		t.Fatal(err)
+	_, err = GridRefsInBounds(grid, MetreSize)
+	if err == nil {
+		t.Fatal("expected error")
 	}
+}
 
-	// subsectors
-	fontSize = 10.0
-	typeFace = getTypeFace(gc, fontSize)
-	fonts.SetFont(gc, typeFace)
-	fm = fonts.GetFaceMetrics(typeFace)
-
-	for i := 0; i <= 99; i++ {
-		subsectorSize := tileSize / 10
-
-		bits := fmt.Sprintf("%02d", i)
-		addX, _ := strconv.Atoi(string(bits[0]))
-		addY, _ := strconv.Atoi(string(bits[1]))
-
-		blX := tileX + (float64(addX) * subsectorSize)
-		blY := float64(imgHeight) - subsectorSize - (tileY + (float64(addY) * subsectorSize))
-
-		textWidth := fonts.GetTextWidth(typeFace, bits)
+func TestParsePrecision(t *testing.T) {
+	tests := map[string]struct {
+		Expected float64
+		Fail     bool
+	}{
+		"100km": {Expected: SquareSize},
+		"5km":   {Expected: QuadrantSize},
+		"1km":   {Expected: KmSquareSize},
+		"100m":  {Expected: HectareSize},
+		"10":    {Expected: TenMetreSize},
+		"1m":    {Expected: MetreSize},
+		"2km":   {Fail: true},
+		"km":    {Fail: true},
+	}
+
+	for s, tt := range tests {
+		actual, err := ParsePrecision(s)
+
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", s)
+			}
+			continue
+		}
 
-		g, err := geom.BoundsGeom(
-			blX,
-			blX+subsectorSize,
-			blY,
-			blY+subsectorSize,
-		)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		l, err := geom.ToLineString(g)
-		if err != nil {
-			t.Fatal(err)
+		if tt.Expected != actual {
+			t.Fatalf("%v expected %v, got %v", s, tt.Expected, actual)
 		}
+	}
 
-		err = geom.DrawLine(gc, l, lineWidth, fillColor, strokeWidth, strokeColor, scale)
+	for _, p := range Precisions {
+		actual, err := ParsePrecision(FormatPrecision(p))
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		labelPos := []float64{
-			blX + (subsectorSize / 2) - (textWidth / 2),
-			blY + (subsectorSize / 2) + ((fm.Ascent - fm.Descent) / 2),
+		if p != actual {
+			t.Fatalf("expected %v, got %v", p, actual)
 		}
+	}
+}
 
-		err = geom.DrawString(gc, labelPos, textRotation, bits)
+func TestGridRefBoundsPrecisions(t *testing.T) {
+	// the cell of a point at each precision is that precision wide and holds it
+	for _, p := range Precisions {
+		g, err := GetGridRef(387221.5, 410715.5, p)
 		if err != nil {
 			t.Fatal(err)
 		}
-	}
-
-	// draw the image
-	err = savePNG("test-output/subsectors.png", m)
-	if err != nil {
-		t.Fatal(err)
-	}
-}
 
-func TestDoOverlap(t *testing.T) {
-	tests := []struct {
-		tl1      []float64
-		br1      []float64
-		tl2      []float64
-		br2      []float64
-		overlaps bool
-	}{
-		{
-			[]float64{0, 2},
-			[]float64{1, 1},
-			[]float64{1, 1},
-			[]float64{0, 2},
-			false,
-		},
-		{
-			[]float64{0, 3},
-			[]float64{2, 1},
-			[]float64{1, 2},
-			[]float64{3, 0},
-			true,
-		},
-	}
-
-	for _, tt := range tests {
-		doOverlap := DoOverlap(tt.tl1, tt.br1, tt.tl2, tt.br2)
-
-		if doOverlap != tt.overlaps {
-			t.Fatalf("expected %+v, got %+v", tt.overlaps, doOverlap)
+		if g.Precision() != p {
+			t.Fatalf("%v expected precision %v, got %v", g, p, g.Precision())
 		}
-	}
-}
-
-func setupImage(width, height int) (*image.RGBA, *draw2dimg.GraphicContext) {
-	m := image.NewRGBA(image.Rect(0, 0, width, height))
-	draw.Draw(m, m.Bounds(), &image.Uniform{white}, image.Point{0, 0}, draw.Src)
-	gc := draw2dimg.NewGraphicContext(m)
-
-	gc.SetDPI(72)
-
-	return m, gc
-}
-
-func getTypeFace(gc *draw2dimg.GraphicContext, fontSize float64) fonts.TypeFace {
-	strokeStyle := draw2d.StrokeStyle{
-		Color: white,
-		Width: lineWidth,
-	}
-	return fonts.TypeFace{
-		StrokeStyle: strokeStyle,
-		Color:       black,
-		Size:        fontSize,
-		FontData:    fontData,
-		Face:        fonts.GetFace(gc, fontData, fontSize),
-	}
-}
 
-func savePNG(fname string, m image.Image) error {
-	dir, _ := path.Split(fname)
-	err := os.MkdirAll(dir, os.ModePerm)
-	if err != nil {
-		return err
-	}
-
-	f, err := os.Create(fname)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+		b, err := g.Bounds()
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	err = draw2dimg.SaveToPngFile(fname, m)
-	if err != nil {
-		return err
+		if b.Xmax-b.Xmin != p || b.Xmin > 387221.5 || b.Xmax <= 387221.5 || b.Ymin > 410715.5 || b.Ymax <= 410715.5 {
+			t.Fatalf("%v expected a %v cell holding the point, got %+v", g, p, b)
+		}
 	}
-
-	return nil
 }
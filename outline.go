@@ -0,0 +1,272 @@
+package nationalgrid
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// MultiPolygon is a set of OSGB36 polygons with closed rings, exteriors
+// counter-clockwise and holes clockwise.
+type MultiPolygon []Polygon
+
+// Outline dissolves grid cells of any precisions into the polygons covering
+// them, with holes where they enclose uncovered areas. Cells that only touch
+// at a corner give separate polygons.
+func Outline(refs []GridRef) (MultiPolygon, error) {
+	s, err := NewCellSet(refs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Outline(), nil
+}
+
+type outlinePoint struct {
+	x, y int64
+}
+
+// outlineSpan is a run of coverage along a line, +1 where the cells are on
+// its left going east / north, -1 where they are on its right.
+type outlineSpan struct {
+	at    int64
+	delta int
+}
+
+// Outline returns the polygons covering the set, see Outline.
+func (s CellSet) Outline() MultiPolygon {
+	// the edges of each cell, counter-clockwise, so shared edges cancel
+	rows := map[int64][]outlineSpan{}
+	cols := map[int64][]outlineSpan{}
+
+	for _, id := range s.ids {
+		b := id.Bounds()
+		x0, y0, x1, y1 := int64(b.Xmin), int64(b.Ymin), int64(b.Xmax), int64(b.Ymax)
+
+		rows[y0] = append(rows[y0], outlineSpan{x0, 1}, outlineSpan{x1, -1})
+		rows[y1] = append(rows[y1], outlineSpan{x0, -1}, outlineSpan{x1, 1})
+		cols[x1] = append(cols[x1], outlineSpan{y0, 1}, outlineSpan{y1, -1})
+		cols[x0] = append(cols[x0], outlineSpan{y0, -1}, outlineSpan{y1, 1})
+	}
+
+	edges := map[outlinePoint][]outlinePoint{}
+
+	for y, spans := range rows {
+		outlineLine(spans, func(from, to int64) {
+			edges[outlinePoint{from, y}] = append(edges[outlinePoint{from, y}], outlinePoint{to, y})
+		})
+	}
+
+	for x, spans := range cols {
+		outlineLine(spans, func(from, to int64) {
+			edges[outlinePoint{x, from}] = append(edges[outlinePoint{x, from}], outlinePoint{x, to})
+		})
+	}
+
+	starts := make([]outlinePoint, 0, len(edges))
+	for p := range edges {
+		starts = append(starts, p)
+	}
+
+	sort.Slice(starts, func(i, j int) bool {
+		if starts[i].y != starts[j].y {
+			return starts[i].y < starts[j].y
+		}
+		return starts[i].x < starts[j].x
+	})
+
+	var exteriors, holes [][][2]float64
+
+	for _, start := range starts {
+		for len(edges[start]) > 0 {
+			ring := outlineTrace(edges, start)
+
+			if ringSignedArea(ring) > 0 {
+				exteriors = append(exteriors, ring)
+			} else {
+				holes = append(holes, ring)
+			}
+		}
+	}
+
+	m := make(MultiPolygon, len(exteriors))
+	for i, ring := range exteriors {
+		m[i] = Polygon{ring}
+	}
+
+	for _, hole := range holes {
+		// a point just inside the covered area, on the left of the hole's
+		// first edge, cells being at least 1m
+		a, b := hole[0], hole[1]
+		dx, dy := float64(sign64(int64(b[0]-a[0]))), float64(sign64(int64(b[1]-a[1])))
+		px := (a[0]+b[0])/2 - dy/4
+		py := (a[1]+b[1])/2 + dx/4
+
+		owner := -1
+		for i, ring := range exteriors {
+			if !(Polygon{ring}).Contains(px, py) {
+				continue
+			}
+			if owner < 0 || ringSignedArea(ring) < ringSignedArea(exteriors[owner]) {
+				owner = i
+			}
+		}
+
+		if owner >= 0 {
+			m[owner] = append(m[owner], hole)
+		}
+	}
+
+	return m
+}
+
+// outlineLine calls edge for each run along a line with non-zero coverage,
+// from and to being in the direction of travel.
+func outlineLine(spans []outlineSpan, edge func(from, to int64)) {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].at < spans[j].at })
+
+	sum, runSign := 0, 0
+	var runStart int64
+
+	for i := 0; i < len(spans); {
+		at := spans[i].at
+		for ; i < len(spans) && spans[i].at == at; i++ {
+			sum += spans[i].delta
+		}
+
+		sign := 0
+		if sum > 0 {
+			sign = 1
+		} else if sum < 0 {
+			sign = -1
+		}
+
+		if sign == runSign {
+			continue
+		}
+
+		switch runSign {
+		case 1:
+			edge(runStart, at)
+		case -1:
+			edge(at, runStart)
+		}
+
+		runStart, runSign = at, sign
+	}
+}
+
+// outlineTrace follows and removes edges from start back to it, turning left
+// where there is a choice so that cells meeting at a corner stay apart.
+func outlineTrace(edges map[outlinePoint][]outlinePoint, start outlinePoint) [][2]float64 {
+	ring := [][2]float64{{float64(start.x), float64(start.y)}}
+
+	var dx, dy int64
+	p := start
+
+	for {
+		next := edges[p]
+
+		choice := 0
+		if len(next) > 1 {
+			best := -2
+			for i, q := range next {
+				ndx, ndy := sign64(q.x-p.x), sign64(q.y-p.y)
+				// cross product, positive for a left turn
+				turn := int(sign64(dx*ndy - dy*ndx))
+				if turn > best {
+					best, choice = turn, i
+				}
+			}
+		}
+
+		q := next[choice]
+		edges[p] = append(next[:choice], next[choice+1:]...)
+
+		if len(edges[p]) == 0 {
+			delete(edges, p)
+		}
+
+		dx, dy = sign64(q.x-p.x), sign64(q.y-p.y)
+		ring = append(ring, [2]float64{float64(q.x), float64(q.y)})
+		p = q
+
+		if p == start {
+			return ring
+		}
+	}
+}
+
+func sign64(v int64) int64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+}
+
+// ringSignedArea returns twice the area of a closed ring, positive when
+// counter-clockwise.
+func ringSignedArea(ring [][2]float64) float64 {
+	var a float64
+
+	for i := 0; i+1 < len(ring); i++ {
+		a += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+
+	return a
+}
+
+// ToWKT returns the polygons as a WKT MULTIPOLYGON.
+func (m MultiPolygon) ToWKT() string {
+	if len(m) == 0 {
+		return "MULTIPOLYGON EMPTY"
+	}
+
+	e := DefaultGeometryEncoder
+
+	polygons := make([]string, 0, len(m))
+	for _, p := range m {
+		rings := make([]string, 0, len(p))
+		for _, ring := range p {
+			points := make([]string, 0, len(ring))
+			for _, c := range ring {
+				points = append(points, e.formatOrd(c[0])+" "+e.formatOrd(c[1]))
+			}
+			rings = append(rings, "("+strings.Join(points, ", ")+")")
+		}
+		polygons = append(polygons, "("+strings.Join(rings, ", ")+")")
+	}
+
+	return "MULTIPOLYGON (" + strings.Join(polygons, ", ") + ")"
+}
+
+// ToGeoJSON returns the polygons as a GeoJSON MultiPolygon geometry in
+// either OSGB36 or WGS84 coordinates.
+func (m MultiPolygon) ToGeoJSON(crs LocationType) ([]byte, error) {
+	project, err := geoJSONProjection(crs)
+	if err != nil {
+		return nil, err
+	}
+
+	coords := make([][][][2]float64, 0, len(m))
+	for _, p := range m {
+		rings := make([][][2]float64, 0, len(p))
+		for _, ring := range p {
+			points := make([][2]float64, 0, len(ring))
+			for _, c := range ring {
+				points = append(points, project(c[0], c[1]))
+			}
+			rings = append(rings, points)
+		}
+		coords = append(coords, rings)
+	}
+
+	return json.Marshal(struct {
+		Type        string           `json:"type"`
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}{"MultiPolygon", coords})
+}
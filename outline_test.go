@@ -0,0 +1,125 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func mustGridRefs(t *testing.T, refs ...string) []GridRef {
+	t.Helper()
+
+	gridRefs := make([]GridRef, 0, len(refs))
+	for _, ref := range refs {
+		g, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gridRefs = append(gridRefs, g)
+	}
+
+	return gridRefs
+}
+
+func TestOutline(t *testing.T) {
+	var ring []string
+	for e := 0; e < 3; e++ {
+		for n := 0; n < 3; n++ {
+			if e != 1 || n != 1 {
+				ring = append(ring, fmt.Sprintf("SD%d%d", e, n))
+			}
+		}
+	}
+
+	tests := map[string]struct {
+		refs     []string
+		expected string
+	}{
+		"single": {
+			refs:     []string{"SD81"},
+			expected: "MULTIPOLYGON (((380000 410000, 390000 410000, 390000 420000, 380000 420000, 380000 410000)))",
+		},
+		"dissolved": {
+			refs:     []string{"SD81", "SD91"},
+			expected: "MULTIPOLYGON (((380000 410000, 400000 410000, 400000 420000, 380000 420000, 380000 410000)))",
+		},
+		"mixed precisions": {
+			refs:     []string{"SD81", "SD9010"},
+			expected: "MULTIPOLYGON (((380000 410000, 391000 410000, 391000 411000, 390000 411000, 390000 420000, 380000 420000, 380000 410000)))",
+		},
+		"corner": {
+			refs:     []string{"SD81", "SD92"},
+			expected: "MULTIPOLYGON (((380000 410000, 390000 410000, 390000 420000, 380000 420000, 380000 410000)), ((390000 420000, 400000 420000, 400000 430000, 390000 430000, 390000 420000)))",
+		},
+		"hole": {
+			refs:     ring,
+			expected: "MULTIPOLYGON (((300000 400000, 330000 400000, 330000 430000, 300000 430000, 300000 400000), (310000 410000, 310000 420000, 320000 420000, 320000 410000, 310000 410000)))",
+		},
+		"empty": {
+			expected: "MULTIPOLYGON EMPTY",
+		},
+	}
+
+	for name, tt := range tests {
+		m, err := Outline(mustGridRefs(t, tt.refs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.ToWKT() != tt.expected {
+			t.Fatalf("%v expected %v, got %v", name, tt.expected, m.ToWKT())
+		}
+	}
+}
+
+func TestOutlineIsland(t *testing.T) {
+	// a 5x5 block of 10km squares with a ring removed, leaving an island
+	var refs []string
+	for e := 0; e < 5; e++ {
+		for n := 0; n < 5; n++ {
+			if (e == 1 || e == 3 || n == 1 || n == 3) && e > 0 && e < 4 && n > 0 && n < 4 {
+				continue
+			}
+			refs = append(refs, fmt.Sprintf("NY%d%d", e, n))
+		}
+	}
+
+	m, err := Outline(mustGridRefs(t, refs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m) != 2 || len(m[0]) != 2 || len(m[1]) != 1 {
+		t.Fatalf("expected a polygon with a hole and an island, got %v", m.ToWKT())
+	}
+
+	if !m[0].Contains(305000, 505000) || m[0].Contains(315000, 515000) || !m[1].Contains(325000, 525000) {
+		t.Fatalf("unexpected outline %v", m.ToWKT())
+	}
+}
+
+func TestMultiPolygonToGeoJSON(t *testing.T) {
+	m, err := Outline(mustGridRefs(t, "SD81"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := m.ToGeoJSON(OSGB36)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"type":"MultiPolygon","coordinates":[[[[380000,410000],[390000,410000],[390000,420000],[380000,420000],[380000,410000]]]]}`
+	if string(b) != expected {
+		t.Fatalf("expected %v, got %v", expected, string(b))
+	}
+
+	b, err = m.ToGeoJSON(WGS84)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), `[[[[-2.`) {
+		t.Fatalf("expected WGS84 coordinates, got %v", string(b))
+	}
+}
@@ -0,0 +1,48 @@
+package nationalgrid
+
+import "math"
+
+// Polygon is an OSGB36 polygon, the first ring being the exterior and any
+// others holes. Rings may be open or closed.
+type Polygon [][][2]float64
+
+// Contains reports whether a point is inside the polygon by the even-odd rule.
+func (p Polygon) Contains(east, north float64) bool {
+	inside := false
+
+	for _, ring := range p {
+		n := len(ring)
+		for i, j := 0, n-1; i < n; j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > north) != (b[1] > north) &&
+				east < (b[0]-a[0])*(north-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+// Bounds returns the extent of the exterior ring.
+func (p Polygon) Bounds() Bounds {
+	b := Bounds{
+		Xmin: math.Inf(1),
+		Ymin: math.Inf(1),
+		Xmax: math.Inf(-1),
+		Ymax: math.Inf(-1),
+	}
+
+	if len(p) == 0 {
+		return b
+	}
+
+	for _, c := range p[0] {
+		b.Xmin = math.Min(b.Xmin, c[0])
+		b.Ymin = math.Min(b.Ymin, c[1])
+		b.Xmax = math.Max(b.Xmax, c[0])
+		b.Ymax = math.Max(b.Ymax, c[1])
+	}
+
+	return b
+}
@@ -0,0 +1,197 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quad keys are strings in which the key of every cell starts with the key of
+// each cell containing it, so sorting keys groups cells by area and a prefix
+// scan finds every cell inside one. The first two characters are the hex
+// Morton code of the 100km square's column and row. Each decimal level then
+// adds two characters, halving the parent into quadrants 0 SW, 1 SE, 2 NW and
+// 3 NE and then splitting the quadrant five by five, numbered 0-9 then a-o
+// west to east and south to north. A quadrant ref is the key of its parent
+// with the quadrant character added.
+
+const quadKeyCells = "0123456789abcdefghijklmno"
+
+var quadKeyQuadrants = []Quadrant{SW, SE, NW, NE}
+
+// QuadKey returns the quad key of the grid ref, e.g. "2518" for SD81.
+func (g GridRef) QuadKey() (string, error) {
+	err := ValidateGridRef(g.String())
+	if err != nil {
+		return "", err
+	}
+
+	gridCoords, ok := NationalGridSquares[g.Square]
+	if !ok {
+		return "", fmt.Errorf("unable to load sector %v", g.Square)
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%02x", morton(int(gridCoords[0]), int(gridCoords[1])))
+
+	digits := len(g.SubSquare) / 2
+
+	for i := 0; i < digits; i++ {
+		e := int(g.SubSquare[i] - '0')
+		n := int(g.SubSquare[digits+i] - '0')
+
+		sb.WriteByte(byte('0' + e/5 + 2*(n/5)))
+		sb.WriteByte(quadKeyCells[(n%5)*5+e%5])
+	}
+
+	if g.Quadrant != "" {
+		for i, q := range quadKeyQuadrants {
+			if q == g.Quadrant {
+				sb.WriteByte(byte('0' + i))
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// ParseQuadKey returns the grid ref of a quad key, keys ending in a quadrant
+// character giving quadrant refs such as "SD8710NE".
+func ParseQuadKey(key string) (GridRef, error) {
+	var g GridRef
+
+	square, e, n, quadrant, err := decodeQuadKey(key)
+	if err != nil {
+		return g, err
+	}
+
+	g.Square = square
+	g.SubSquare = strings.Join(e, "") + strings.Join(n, "")
+
+	if quadrant >= 0 {
+		g.Quadrant = quadKeyQuadrants[quadrant]
+	}
+
+	return g, nil
+}
+
+// QuadKeyBounds returns the OSGB36 extent of a quad key at any level.
+func QuadKeyBounds(key string) (Bounds, error) {
+	var b Bounds
+
+	square, e, n, quadrant, err := decodeQuadKey(key)
+	if err != nil {
+		return b, err
+	}
+
+	b, err = GridRef{
+		Square:    square,
+		SubSquare: strings.Join(e, "") + strings.Join(n, ""),
+	}.Bounds()
+	if err != nil {
+		return b, err
+	}
+
+	if quadrant < 0 {
+		return b, nil
+	}
+
+	half := (b.Xmax - b.Xmin) / 2
+
+	b.Xmin += float64(quadrant%2) * half
+	b.Ymin += float64(quadrant/2) * half
+	b.Xmax = b.Xmin + half
+	b.Ymax = b.Ymin + half
+
+	return b, nil
+}
+
+// QuadKeyRange returns the range [start, end) of the keys of every cell
+// within a quad key's cell, including the cell itself.
+func QuadKeyRange(key string) (string, string) {
+	if key == "" {
+		return "", ""
+	}
+
+	end := []byte(key)
+	end[len(end)-1]++
+
+	return key, string(end)
+}
+
+// decodeQuadKey returns the square, easting and northing digits and trailing
+// quadrant of a key, the quadrant being -1 for none.
+func decodeQuadKey(key string) (string, []string, []string, int, error) {
+	if len(key) < 2 || len(key) > 2+maxDigits {
+		return "", nil, nil, -1, fmt.Errorf("invalid quad key %v", key)
+	}
+
+	code, err := strconv.ParseUint(key[:2], 16, 8)
+	if err != nil {
+		return "", nil, nil, -1, fmt.Errorf("invalid quad key %v", key)
+	}
+
+	col, row := unmorton(int(code))
+
+	square := ""
+	for k, v := range NationalGridSquares {
+		if int(v[0]) == col && int(v[1]) == row {
+			square = k
+			break
+		}
+	}
+
+	if square == "" {
+		return "", nil, nil, -1, fmt.Errorf("quad key %v is outside the national grid", key)
+	}
+
+	var e, n []string
+	quadrant := -1
+
+	rest := key[2:]
+	for len(rest) > 0 {
+		q := int(rest[0] - '0')
+		if q < 0 || q > 3 {
+			return "", nil, nil, -1, fmt.Errorf("invalid quad key %v", key)
+		}
+
+		if len(rest) == 1 {
+			quadrant = q
+			break
+		}
+
+		c := strings.IndexByte(quadKeyCells, rest[1])
+		if c < 0 {
+			return "", nil, nil, -1, fmt.Errorf("invalid quad key %v", key)
+		}
+
+		e = append(e, strconv.Itoa((q%2)*5+c%5))
+		n = append(n, strconv.Itoa((q/2)*5+c/5))
+
+		rest = rest[2:]
+	}
+
+	return square, e, n, quadrant, nil
+}
+
+// morton interleaves the low 4 bits of x and y, x in the even bits.
+func morton(x, y int) int {
+	m := 0
+	for i := 0; i < 4; i++ {
+		m |= (x >> i & 1) << (2 * i)
+		m |= (y >> i & 1) << (2*i + 1)
+	}
+
+	return m
+}
+
+func unmorton(m int) (int, int) {
+	x, y := 0, 0
+	for i := 0; i < 4; i++ {
+		x |= (m >> (2 * i) & 1) << i
+		y |= (m >> (2*i + 1) & 1) << i
+	}
+
+	return x, y
+}
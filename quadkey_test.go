@@ -0,0 +1,127 @@
+package nationalgrid
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestQuadKey(t *testing.T) {
+	tests := map[string]string{
+		"SD":           "25",
+		"SDSE":         "251",
+		"SD81":         "2518",
+		"SD91NW":       "25192",
+		"SD8710":       "251812",
+		"SD8710NE":     "2518123",
+		"SD8722110715": "2518122c0721",
+		"SV00":         "0000",
+		"HP99":         "b03o",
+	}
+
+	for ref, expected := range tests {
+		g, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := g.QuadKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected != actual {
+			t.Fatalf("%v expected %v, got %v", ref, expected, actual)
+		}
+
+		decoded, err := ParseQuadKey(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g != decoded {
+			t.Fatalf("%v expected %+v, got %+v", ref, g, decoded)
+		}
+
+		expectedBounds, err := g.Bounds()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := QuadKeyBounds(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expectedBounds != b {
+			t.Fatalf("%v expected %+v, got %+v", ref, expectedBounds, b)
+		}
+	}
+}
+
+func TestQuadKeyContainment(t *testing.T) {
+	// every 1km square of SD91NW, and one outside it
+	parent, err := GridRef{Square: "SD", SubSquare: "91", Quadrant: NW}.QuadKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := GridRef{Square: "SD", SubSquare: "91", Quadrant: NW}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := GridRefsInBounds(b, KmSquareSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, end := QuadKeyRange(parent)
+
+	var keys []string
+	for _, ref := range append(refs, GridRef{Square: "SD", SubSquare: "9514"}) {
+		key, err := ref.QuadKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	inside := 0
+	for _, key := range keys {
+		if key >= start && key < end {
+			inside++
+
+			if !strings.HasPrefix(key, parent) {
+				t.Fatalf("expected %v to start with %v", key, parent)
+			}
+		}
+	}
+
+	if inside != 25 {
+		t.Fatalf("expected 25 keys in range, got %v", inside)
+	}
+}
+
+func TestQuadKeyErrors(t *testing.T) {
+	// a bad quadrant, not a square, bad characters
+	for _, key := range []string{"254", "2518114", "ff", "25z", "2", "2518122c072100"} {
+		_, err := ParseQuadKey(key)
+		if err == nil {
+			t.Fatalf("%v expected error", key)
+		}
+	}
+
+	// the SE 50km quadrant of SD
+	b, err := QuadKeyBounds("251")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Bounds{Xmin: 350000, Xmax: 400000, Ymin: 400000, Ymax: 450000}
+	if expected != b {
+		t.Fatalf("expected %+v, got %+v", expected, b)
+	}
+}
@@ -0,0 +1,117 @@
+package nationalgrid
+
+import (
+	"fmt"
+)
+
+// isQuadrantPrecision reports whether a cell size is one of the
+// QuadrantPrecisions.
+func isQuadrantPrecision(precision float64) bool {
+	for _, p := range QuadrantPrecisions {
+		if precision == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quadrantAt returns the quadrant of b containing a point, points on the
+// centre lines falling to the north and east.
+func quadrantAt(b Bounds, east, north float64) Quadrant {
+	half := (b.Xmax - b.Xmin) / 2
+
+	switch {
+	case east < b.Xmin+half && north < b.Ymin+half:
+		return SW
+	case east < b.Xmin+half:
+		return NW
+	case north < b.Ymin+half:
+		return SE
+	default:
+		return NE
+	}
+}
+
+// QuadrantAt returns the quadrant of the cell containing an OSGB36 easting /
+// northing, e.g. NE for 387600, 410600 in SD8710.
+func (g GridRef) QuadrantAt(east, north float64) (Quadrant, error) {
+	if g.Quadrant != "" {
+		return "", fmt.Errorf("%v is already a quadrant", g)
+	}
+
+	if len(g.SubSquare) >= maxDigits {
+		return "", fmt.Errorf("a 1m grid ref has no quadrants %v", g)
+	}
+
+	b, err := g.Bounds()
+	if err != nil {
+		return "", err
+	}
+
+	if east < b.Xmin || east >= b.Xmax || north < b.Ymin || north >= b.Ymax {
+		return "", fmt.Errorf("%v, %v is outside %v", east, north, g)
+	}
+
+	return quadrantAt(b, east, north), nil
+}
+
+// Quadrants returns the SW, SE, NW and NE quadrants of the cell.
+func (g GridRef) Quadrants() ([]GridRef, error) {
+	if g.Quadrant != "" {
+		return nil, fmt.Errorf("%v is already a quadrant", g)
+	}
+
+	err := ValidateGridRef(g.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(g.SubSquare) >= maxDigits {
+		return nil, fmt.Errorf("a 1m grid ref has no quadrants %v", g)
+	}
+
+	quadrants := make([]GridRef, 0, len(quadKeyQuadrants))
+
+	for _, q := range quadKeyQuadrants {
+		quadrants = append(quadrants, GridRef{
+			Square:    g.Square,
+			SubSquare: g.SubSquare,
+			Quadrant:  q,
+		})
+	}
+
+	return quadrants, nil
+}
+
+// GridRefFromBounds returns the grid ref whose cell is exactly b, the
+// inverse of GridRef.Bounds.
+func GridRefFromBounds(b Bounds) (GridRef, error) {
+	var g GridRef
+
+	size := b.Xmax - b.Xmin
+	if size != b.Ymax-b.Ymin {
+		return g, fmt.Errorf("bounds %+v are not square", b)
+	}
+
+	err := ValidatePrecision(size)
+	if err != nil {
+		return g, err
+	}
+
+	g, err = GetGridRef(b.Xmin+size/2, b.Ymin+size/2, size)
+	if err != nil {
+		return g, err
+	}
+
+	cell, err := g.Bounds()
+	if err != nil {
+		return g, err
+	}
+
+	if cell != b {
+		return GridRef{}, fmt.Errorf("bounds %+v are not aligned to the grid", b)
+	}
+
+	return g, nil
+}
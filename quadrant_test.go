@@ -0,0 +1,191 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGridRefQuadrants(t *testing.T) {
+	tests := map[string]struct {
+		Expected []string
+		Fail     bool
+	}{
+		"SD": {
+			Expected: []string{"SDSW", "SDSE", "SDNW", "SDNE"},
+		},
+		"SD8710": {
+			Expected: []string{"SD8710SW", "SD8710SE", "SD8710NW", "SD8710NE"},
+		},
+		"SD87221071": {
+			Expected: []string{"SD87221071SW", "SD87221071SE", "SD87221071NW", "SD87221071NE"},
+		},
+		"SD8710NE": {
+			Fail: true,
+		},
+		"SD8722110715": {
+			Fail: true,
+		},
+	}
+
+	for ref, tt := range tests {
+		g, err := ParseGridRef(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		quadrants, err := g.Quadrants()
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", ref)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parent, err := g.Bounds()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actual []string
+		for _, q := range quadrants {
+			actual = append(actual, q.String())
+
+			b, err := q.Bounds()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.Xmin < parent.Xmin || b.Xmax > parent.Xmax || b.Ymin < parent.Ymin || b.Ymax > parent.Ymax {
+				t.Fatalf("expected %v within %+v, got %+v", q, parent, b)
+			}
+		}
+
+		if fmt.Sprint(tt.Expected) != fmt.Sprint(actual) {
+			t.Fatalf("%v expected %+v, got %+v", ref, tt.Expected, actual)
+		}
+	}
+}
+
+func TestGridRefQuadrantAt(t *testing.T) {
+	tests := map[string]struct {
+		Ref      string
+		East     float64
+		North    float64
+		Expected Quadrant
+		Fail     bool
+	}{
+		"square": {
+			Ref:      "SD",
+			East:     387221,
+			North:    410715,
+			Expected: SE,
+		},
+		"km": {
+			Ref:      "SD8710",
+			East:     387600,
+			North:    410600,
+			Expected: NE,
+		},
+		"centre": {
+			Ref:      "SD8710",
+			East:     387500,
+			North:    410500,
+			Expected: NE,
+		},
+		"corner": {
+			Ref:      "SD8710",
+			East:     387000,
+			North:    410000,
+			Expected: SW,
+		},
+		"outside": {
+			Ref:   "SD8710",
+			East:  388000,
+			North: 410000,
+			Fail:  true,
+		},
+		"quadrant": {
+			Ref:   "SD8710NE",
+			East:  387600,
+			North: 410600,
+			Fail:  true,
+		},
+		"metre": {
+			Ref:   "SD8722110715",
+			East:  387221,
+			North: 410715,
+			Fail:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		g, err := ParseGridRef(tt.Ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := g.QuadrantAt(tt.East, tt.North)
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.Expected != actual {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, actual)
+		}
+	}
+}
+
+func TestGridRefFromBounds(t *testing.T) {
+	// every level of a point, quadrants included, round trips
+	for _, precisions := range [][]float64{Precisions, QuadrantPrecisions} {
+		for _, p := range precisions {
+			g, err := GetGridRef(387221, 410715, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := g.Bounds()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.Xmax-b.Xmin != p {
+				t.Fatalf("%v expected width %v, got %+v", g, p, b)
+			}
+
+			actual, err := GridRefFromBounds(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if g != actual {
+				t.Fatalf("expected %+v, got %+v", g, actual)
+			}
+		}
+	}
+
+	tests := map[string]Bounds{
+		"not square":  {Xmin: 387000, Xmax: 388000, Ymin: 410000, Ymax: 410500},
+		"not aligned": {Xmin: 387250, Xmax: 387750, Ymin: 410000, Ymax: 410500},
+		"precision":   {Xmin: 386000, Xmax: 388000, Ymin: 410000, Ymax: 412000},
+		"outside":     {Xmin: -1000, Xmax: 0, Ymin: 0, Ymax: 1000},
+	}
+
+	for name, b := range tests {
+		_, err := GridRefFromBounds(b)
+		if err == nil {
+			t.Fatalf("%v expected error", name)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/rockwell-uk/go-geos-draw/geom"
+	"github.com/rockwell-uk/go-text/fonts"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+type BreakMethod int
+
+const (
+	// Quantile puts an equal number of values in each class.
+	Quantile BreakMethod = iota
+	// EqualInterval splits the range of values into classes of equal width.
+	EqualInterval
+	// Manual uses the breaks given in ChoroplethOptions.Breaks.
+	Manual
+)
+
+func (m BreakMethod) String() string {
+	switch m {
+	case Quantile:
+		return "quantile"
+	case EqualInterval:
+		return "equal"
+	case Manual:
+		return "manual"
+	}
+
+	return fmt.Sprintf("BreakMethod(%d)", int(m))
+}
+
+// Ramp is a list of colours from the lowest class to the highest, stretched
+// to the number of classes.
+type Ramp []color.RGBA
+
+var (
+	RampBlues = Ramp{
+		{0xEF, 0xF3, 0xFF, 0xFF},
+		{0x08, 0x45, 0x94, 0xFF},
+	}
+	RampGreens = Ramp{
+		{0xED, 0xF8, 0xE9, 0xFF},
+		{0x00, 0x6D, 0x2C, 0xFF},
+	}
+	RampYlOrRd = Ramp{
+		{0xFF, 0xFF, 0xB2, 0xFF},
+		{0xFD, 0x8D, 0x3C, 0xFF},
+		{0xBD, 0x00, 0x26, 0xFF},
+	}
+)
+
+// Colors returns n colours evenly spaced along the ramp.
+func (r Ramp) Colors(n int) []color.RGBA {
+	colors := make([]color.RGBA, n)
+
+	if len(r) == 0 {
+		return colors
+	}
+
+	for i := range colors {
+		if n == 1 || len(r) == 1 {
+			colors[i] = r[len(r)-1]
+			continue
+		}
+
+		pos := float64(i) / float64(n-1) * float64(len(r)-1)
+		j := int(math.Floor(pos))
+		if j >= len(r)-1 {
+			colors[i] = r[len(r)-1]
+			continue
+		}
+
+		colors[i] = lerpColor(r[j], r[j+1], pos-float64(j))
+	}
+
+	return colors
+}
+
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), lerp(a.A, b.A)}
+}
+
+// ValueLabelFunc returns the label drawn at the centre of a valued cell, "" for none.
+type ValueLabelFunc func(ref nationalgrid.GridRef, value float64) string
+
+// LabelValue labels cells with their value.
+func LabelValue(_ nationalgrid.GridRef, value float64) string {
+	return formatNumber(value)
+}
+
+type ChoroplethOptions struct {
+	Classes int
+	Method  BreakMethod
+	// Breaks are the ascending upper bounds of each class for Manual, values
+	// above the last break falling in the last class.
+	Breaks []float64
+	Ramp   Ramp
+	// Label labels the valued cells, nil for none.
+	Label ValueLabelFunc
+	// Legend draws a key of the classes in the top left corner.
+	Legend      bool
+	LegendTitle string
+	// FontData and FontSize are used for the legend and value labels of PNGs,
+	// SVGs being styled by the "legend" and "value" classes.
+	FontData  draw2d.FontData
+	FontSize  float64
+	FontColor color.RGBA
+}
+
+// DefaultChoroplethOptions puts values into 5 quantile classes on a blue ramp
+// with a legend.
+func DefaultChoroplethOptions() ChoroplethOptions {
+	return ChoroplethOptions{
+		Classes: 5,
+		Method:  Quantile,
+		Ramp:    RampBlues,
+		Legend:  true,
+		FontData: draw2d.FontData{
+			Name:   "regular",
+			Family: draw2d.FontFamilySans,
+			Style:  draw2d.FontStyleNormal,
+		},
+		FontSize:  10,
+		FontColor: black,
+	}
+}
+
+// DefaultChoroplethCSS styles the classes, value labels and legend of an SVG
+// choropleth, the fill of each class being set on the cell itself.
+const DefaultChoroplethCSS = DefaultSVGCSS + `.choropleth rect { stroke: none; }
+.value { font-family: sans-serif; font-size: 8px; text-anchor: middle; dominant-baseline: central; }
+.legend text { font-family: sans-serif; font-size: 10px; dominant-baseline: central; }
+.legend .title { font-weight: bold; }
+.legend rect { stroke: #000; stroke-width: 0.5; }
+`
+
+// ClassBreaks returns the ascending upper bound of each class of values, the
+// last being the largest value. Repeated breaks, from values with fewer
+// distinct values than classes, are dropped so no class is empty.
+func ClassBreaks(values []float64, classes int, method BreakMethod) ([]float64, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values to classify")
+	}
+
+	if classes <= 0 {
+		return nil, fmt.Errorf("invalid number of classes %v", classes)
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	breaks := make([]float64, classes)
+
+	switch method {
+	case Quantile:
+		n := len(sorted)
+		for i := range breaks {
+			j := int(math.Ceil(float64((i+1)*n)/float64(classes))) - 1
+			breaks[i] = sorted[j]
+		}
+
+	case EqualInterval:
+		lo, hi := sorted[0], sorted[len(sorted)-1]
+		for i := range breaks {
+			breaks[i] = lo + float64(i+1)*(hi-lo)/float64(classes)
+		}
+		breaks[classes-1] = hi
+
+	case Manual:
+		return nil, fmt.Errorf("manual breaks are not computed")
+
+	default:
+		return nil, fmt.Errorf("unknown break method %v", method)
+	}
+
+	unique := breaks[:1]
+	for _, b := range breaks[1:] {
+		if b != unique[len(unique)-1] {
+			unique = append(unique, b)
+		}
+	}
+
+	return unique, nil
+}
+
+// classOf returns the index of the first class whose upper bound is not below v.
+func classOf(breaks []float64, v float64) int {
+	i := sort.SearchFloat64s(breaks, v)
+	if i >= len(breaks) {
+		return len(breaks) - 1
+	}
+
+	return i
+}
+
+type choropleth struct {
+	refs   []nationalgrid.GridRef
+	values map[nationalgrid.GridRef]float64
+	breaks []float64
+	colors []color.RGBA
+	lo     float64
+}
+
+// newChoropleth classifies the values, ordering the cells by ref.
+func newChoropleth(values map[nationalgrid.GridRef]float64, opts ChoroplethOptions) (choropleth, error) {
+	var c choropleth
+
+	if len(opts.Ramp) == 0 {
+		return c, fmt.Errorf("empty colour ramp")
+	}
+
+	c.values = values
+	vs := make([]float64, 0, len(values))
+
+	for ref, v := range values {
+		err := nationalgrid.ValidateGridRef(ref.String())
+		if err != nil {
+			return c, err
+		}
+
+		c.refs = append(c.refs, ref)
+		vs = append(vs, v)
+	}
+
+	sort.Slice(c.refs, func(i, j int) bool {
+		return c.refs[i].String() < c.refs[j].String()
+	})
+
+	var err error
+
+	if opts.Method == Manual {
+		if len(opts.Breaks) == 0 {
+			return c, fmt.Errorf("manual breaks are required")
+		}
+		if !sort.Float64sAreSorted(opts.Breaks) {
+			return c, fmt.Errorf("manual breaks must be ascending %v", opts.Breaks)
+		}
+		c.breaks = opts.Breaks
+	} else {
+		c.breaks, err = ClassBreaks(vs, opts.Classes, opts.Method)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	c.colors = opts.Ramp.Colors(len(c.breaks))
+
+	if len(vs) > 0 {
+		sort.Float64s(vs)
+		c.lo = vs[0]
+	}
+
+	return c, nil
+}
+
+// legend returns the label of each class, "lo - hi".
+func (c choropleth) legend() []string {
+	labels := make([]string, len(c.breaks))
+
+	lo := c.lo
+	for i, hi := range c.breaks {
+		if i == 0 && lo > hi {
+			lo = hi
+		}
+		labels[i] = formatNumber(lo) + " - " + formatNumber(hi)
+		if lo == hi {
+			// a class of a single value
+			labels[i] = formatNumber(hi)
+		}
+		lo = hi
+	}
+
+	return labels
+}
+
+// Choropleth fills each cell with the colour of its value's class, then draws
+// the grid described by grid over it.
+func Choropleth(values map[nationalgrid.GridRef]float64, grid Options, opts ChoroplethOptions) (image.Image, error) {
+	c, err := newChoropleth(values, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	toPixel, err := projection(grid.Width, grid.Height, grid.Extent)
+	if err != nil {
+		return nil, err
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, grid.Width, grid.Height))
+	draw.Draw(m, m.Bounds(), &image.Uniform{grid.Background}, image.Point{}, draw.Src)
+
+	gc := draw2dimg.NewGraphicContext(m)
+	gc.SetDPI(72)
+
+	for _, ref := range c.refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return nil, err
+		}
+
+		x0, y0 := toPixel(b.Xmin, b.Ymax)
+		x1, y1 := toPixel(b.Xmax, b.Ymin)
+
+		fillRect(gc, x0, y0, x1, y1, c.colors[classOf(c.breaks, c.values[ref])])
+	}
+
+	err = DrawGrid(gc, grid)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Label == nil && !opts.Legend {
+		return m, nil
+	}
+
+	typeFace, fm, err := setLabelFont(gc, Style{
+		FontData:  opts.FontData,
+		FontSize:  opts.FontSize,
+		FontColor: opts.FontColor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	textOffset := (fm.Ascent - fm.Descent) / 2
+
+	if opts.Label != nil {
+		for _, ref := range c.refs {
+			label := opts.Label(ref, c.values[ref])
+			if label == "" {
+				continue
+			}
+
+			b, err := ref.Bounds()
+			if err != nil {
+				return nil, err
+			}
+
+			x, y := toPixel(b.Xmin+(b.Xmax-b.Xmin)/2, b.Ymin+(b.Ymax-b.Ymin)/2)
+
+			err = geom.DrawString(gc, []float64{x - fonts.GetTextWidth(typeFace, label)/2, y + textOffset}, 0, label)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Legend {
+		err = drawLegend(gc, c, opts, typeFace, textOffset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+const (
+	legendMargin = 10.0
+	legendRow    = 18.0
+	legendSwatch = 24.0
+)
+
+func drawLegend(gc *draw2dimg.GraphicContext, c choropleth, opts ChoroplethOptions, typeFace fonts.TypeFace, textOffset float64) error {
+	labels := c.legend()
+
+	width := 0.0
+	for _, label := range append([]string{opts.LegendTitle}, labels...) {
+		w := fonts.GetTextWidth(typeFace, label)
+		if w > width {
+			width = w
+		}
+	}
+
+	rows := float64(len(labels))
+	if opts.LegendTitle != "" {
+		rows++
+	}
+
+	// background box
+	fillRect(gc, legendMargin, legendMargin, legendMargin*3.5+legendSwatch+width, legendMargin*3+rows*legendRow, white)
+
+	y := legendMargin * 2
+	x := legendMargin * 2
+
+	if opts.LegendTitle != "" {
+		err := geom.DrawString(gc, []float64{x, y + legendRow/2 + textOffset}, 0, opts.LegendTitle)
+		if err != nil {
+			return err
+		}
+		y += legendRow
+	}
+
+	for i, label := range labels {
+		fillRect(gc, x, y+2, x+legendSwatch, y+legendRow-2, c.colors[i])
+
+		err := geom.DrawString(gc, []float64{x + legendSwatch + legendMargin/2, y + legendRow/2 + textOffset}, 0, label)
+		if err != nil {
+			return err
+		}
+		y += legendRow
+	}
+
+	return nil
+}
+
+// fillRect fills a rectangle in pixels, keeping the fill colour of text.
+func fillRect(gc *draw2dimg.GraphicContext, x0, y0, x1, y1 float64, c color.RGBA) {
+	gc.Save()
+	defer gc.Restore()
+
+	gc.SetFillColor(c)
+	gc.MoveTo(x0, y0)
+	gc.LineTo(x1, y0)
+	gc.LineTo(x1, y1)
+	gc.LineTo(x0, y1)
+	gc.Close()
+	gc.Fill()
+}
+
+// WriteChoroplethSVG writes the classified cells as an SVG document, each
+// cell having a "class-<n>" class and fill, with the grid described by grid
+// drawn over them.
+func WriteChoroplethSVG(w io.Writer, values map[nationalgrid.GridRef]float64, grid SVGOptions, opts ChoroplethOptions) error {
+	c, err := newChoropleth(values, opts)
+	if err != nil {
+		return err
+	}
+
+	toPixel, err := projection(grid.Width, grid.Height, grid.Extent)
+	if err != nil {
+		return err
+	}
+
+	err = nationalgrid.ValidatePrecision(grid.Precision)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	writeSVGHeader(bw, grid)
+
+	bw.WriteString("<g class=\"choropleth\">\n")
+
+	for _, ref := range c.refs {
+		x, y, width, height, err := svgCell(ref, toPixel)
+		if err != nil {
+			return err
+		}
+
+		i := classOf(c.breaks, c.values[ref])
+
+		fmt.Fprintf(bw, "<rect class=\"class-%d\" data-ref=\"%s\" data-value=\"%s\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"%s\"/>\n",
+			i, ref, formatNumber(c.values[ref]), formatNumber(x), formatNumber(y), formatNumber(width), formatNumber(height), svgColor(c.colors[i]))
+	}
+
+	bw.WriteString("</g>\n")
+
+	err = writeSVGLevels(bw, grid, toPixel)
+	if err != nil {
+		return err
+	}
+
+	if opts.Label != nil {
+		bw.WriteString("<g class=\"values\">\n")
+
+		for _, ref := range c.refs {
+			x, y, width, height, err := svgCell(ref, toPixel)
+			if err != nil {
+				return err
+			}
+
+			writeSVGLabel(bw, "value", opts.Label(ref, c.values[ref]), x+width/2, y+height/2)
+		}
+
+		bw.WriteString("</g>\n")
+	}
+
+	err = writeSVGOverlays(bw, grid.Overlays, toPixel)
+	if err != nil {
+		return err
+	}
+
+	if opts.Legend {
+		writeSVGLegend(bw, c, opts)
+	}
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
+
+func writeSVGLegend(bw *bufio.Writer, c choropleth, opts ChoroplethOptions) {
+	bw.WriteString("<g class=\"legend\">\n")
+
+	y := legendMargin
+	x := legendMargin
+
+	if opts.LegendTitle != "" {
+		writeSVGLabel(bw, "title", opts.LegendTitle, x, y+legendRow/2)
+		y += legendRow
+	}
+
+	for i, label := range c.legend() {
+		fmt.Fprintf(bw, "<rect class=\"class-%d\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"%s\"/>\n",
+			i, formatNumber(x), formatNumber(y+2), formatNumber(legendSwatch), formatNumber(legendRow-4), svgColor(c.colors[i]))
+		writeSVGLabel(bw, "class-label", label, x+legendSwatch+legendMargin/2, y+legendRow/2)
+		y += legendRow
+	}
+
+	bw.WriteString("</g>\n")
+}
+
+func svgColor(c color.RGBA) string {
+	if c.A == 0xFF {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+
+	return fmt.Sprintf("rgba(%d,%d,%d,%s)", c.R, c.G, c.B, formatNumber(float64(c.A)/0xFF))
+}
@@ -0,0 +1,252 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"reflect"
+	"testing"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func TestClassBreaks(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := map[string]struct {
+		Values   []float64
+		Method   BreakMethod
+		Classes  int
+		Expected []float64
+		Fail     bool
+	}{
+		"repeated": {
+			Values:   []float64{1, 1, 1, 1, 1, 1, 2, 2},
+			Method:   Quantile,
+			Classes:  5,
+			Expected: []float64{1, 2},
+		},
+		"constant": {
+			Values:   []float64{3, 3, 3},
+			Method:   EqualInterval,
+			Classes:  3,
+			Expected: []float64{3},
+		},
+		"quantile": {
+			Method:   Quantile,
+			Classes:  4,
+			Expected: []float64{3, 5, 8, 10},
+		},
+		"equal": {
+			Method:   EqualInterval,
+			Classes:  3,
+			Expected: []float64{4, 7, 10},
+		},
+		"manual": {
+			Method:  Manual,
+			Classes: 3,
+			Fail:    true,
+		},
+		"classes": {
+			Method:  Quantile,
+			Classes: 0,
+			Fail:    true,
+		},
+	}
+
+	for name, tt := range tests {
+		v := values
+		if tt.Values != nil {
+			v = tt.Values
+		}
+
+		actual, err := ClassBreaks(v, tt.Classes, tt.Method)
+
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.Expected, actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.Expected, actual)
+		}
+	}
+}
+
+func TestChoroplethLegendRepeatedValues(t *testing.T) {
+	values := map[nationalgrid.GridRef]float64{
+		{Square: "SD"}: 1,
+		{Square: "SE"}: 1,
+		{Square: "SJ"}: 1,
+		{Square: "SK"}: 2,
+	}
+
+	opts := DefaultChoroplethOptions()
+	opts.Classes = 5
+
+	c, err := newChoropleth(values, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"1", "1 - 2"}
+	if !reflect.DeepEqual(expected, c.legend()) {
+		t.Fatalf("expected %+v, got %+v", expected, c.legend())
+	}
+}
+
+func TestRampColors(t *testing.T) {
+	r := Ramp{
+		{0x00, 0x00, 0x00, 0xFF},
+		{0xFF, 0xFF, 0xFF, 0xFF},
+	}
+
+	expected := []color.RGBA{
+		{0x00, 0x00, 0x00, 0xFF},
+		{0x80, 0x80, 0x80, 0xFF},
+		{0xFF, 0xFF, 0xFF, 0xFF},
+	}
+
+	actual := r.Colors(3)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func choroplethValues() map[nationalgrid.GridRef]float64 {
+	return map[nationalgrid.GridRef]float64{
+		{Square: "SD", SubSquare: "90"}: 1,
+		{Square: "SD", SubSquare: "91"}: 5,
+		{Square: "SD", SubSquare: "81"}: 12,
+		{Square: "SD", SubSquare: "80"}: 40,
+	}
+}
+
+func TestChoropleth(t *testing.T) {
+	sd, err := nationalgrid.GridRef{Square: "SD"}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid := DefaultOptions()
+	grid.Width = 500
+	grid.Height = 500
+	grid.Extent = sd
+	grid.Precision = nationalgrid.SubSquareSize
+	grid.Styles = map[float64]Style{
+		nationalgrid.SquareSize:    DefaultStyles[nationalgrid.SquareSize],
+		nationalgrid.SubSquareSize: {LineWidth: 1, LineColor: black},
+	}
+
+	opts := DefaultChoroplethOptions()
+	opts.Method = Manual
+	opts.Breaks = []float64{2, 10, 50}
+	opts.Ramp = Ramp{
+		{0xFF, 0x00, 0x00, 0xFF},
+		{0x00, 0x00, 0xFF, 0xFF},
+	}
+	opts.Label = LabelValue
+	opts.LegendTitle = "Records"
+
+	m, err := Choropleth(choroplethValues(), grid, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SD90 is in the first, red class, SD91 in the middle and SD80 in the
+	// last, blue class, each sampled clear of its label, and empty cells are
+	// left clear
+	expected := map[[2]int]color.RGBA{
+		{460, 490}: {0xFF, 0x00, 0x00, 0xFF},
+		{455, 445}: {0x80, 0x00, 0x80, 0xFF},
+		{410, 490}: {0x00, 0x00, 0xFF, 0xFF},
+		{310, 160}: white,
+	}
+
+	for p, c := range expected {
+		if m.At(p[0], p[1]) != c {
+			t.Fatalf("expected %v at %v, got %v", c, p, m.At(p[0], p[1]))
+		}
+	}
+
+	err = savePNG("../test-output/choropleth.png", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteChoroplethSVG(t *testing.T) {
+	sd, err := nationalgrid.GridRef{Square: "SD"}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid := DefaultSVGOptions()
+	grid.Width = 500
+	grid.Height = 500
+	grid.Extent = sd
+	grid.CSS = DefaultChoroplethCSS
+
+	opts := DefaultChoroplethOptions()
+	opts.Classes = 2
+	opts.Label = LabelValue
+	opts.LegendTitle = "Records"
+
+	var buf bytes.Buffer
+
+	err = WriteChoroplethSVG(&buf, choroplethValues(), grid, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := "testdata/choropleth.svg"
+
+	if *update {
+		err = os.WriteFile(golden, buf.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("output differs from %v, run go test -update to regenerate\n%s", golden, buf.Bytes())
+	}
+}
+
+func TestChoroplethErrors(t *testing.T) {
+	grid := DefaultOptions()
+
+	opts := DefaultChoroplethOptions()
+	opts.Method = Manual
+
+	_, err := Choropleth(choroplethValues(), grid, opts)
+	if err == nil {
+		t.Fatal("expected error for missing manual breaks")
+	}
+
+	opts.Breaks = []float64{10, 2}
+
+	_, err = Choropleth(choroplethValues(), grid, opts)
+	if err == nil {
+		t.Fatal("expected error for descending manual breaks")
+	}
+
+	opts = DefaultChoroplethOptions()
+	opts.Ramp = nil
+
+	_, err = Choropleth(choroplethValues(), grid, opts)
+	if err == nil {
+		t.Fatal("expected error for empty ramp")
+	}
+}
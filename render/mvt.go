@@ -0,0 +1,404 @@
+package render
+
+import (
+	"math"
+	"net/http"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+// MVTExtent is the size of a vector tile in tile units.
+const MVTExtent = 4096
+
+// MVT layer names.
+const (
+	MVTCellsLayer  = "cells"
+	MVTLabelsLayer = "labels"
+)
+
+// mvtBuffer is how far outside the tile, in tile units, cell polygons are kept.
+const mvtBuffer = 64
+
+// vector tile protobuf field numbers and geometry types, see
+// https://github.com/mapbox/vector-tile-spec/tree/master/2.1
+const (
+	mvtTileLayers = 3
+
+	mvtLayerVersion  = 15
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+
+	mvtFeatureID       = 1
+	mvtFeatureTags     = 2
+	mvtFeatureType     = 3
+	mvtFeatureGeometry = 4
+
+	mvtValueString = 1
+	mvtValueDouble = 3
+
+	mvtPoint   = 1
+	mvtPolygon = 3
+
+	mvtMoveTo    = 1
+	mvtLineTo    = 2
+	mvtClosePath = 7
+
+	wireVarint = 0
+	wireBytes  = 2
+	wireFixed  = 1
+)
+
+// VectorTile returns a Mapbox Vector Tile of tile z/x/y with a "cells" layer
+// of grid cell polygons and a "labels" layer of points at the cell centres,
+// for the levels Tile would draw. Features have ref, precision and level
+// attributes, level being the precision formatted as "10km".
+func VectorTile(z, x, y int, opts TileOptions) ([]byte, error) {
+	err := validateTile(z, x, y, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := newMVTLayer(MVTCellsLayer)
+	labels := newMVTLayer(MVTLabelsLayer)
+
+	level := TileLevel(z, x, y, opts)
+
+	e := clipBounds(tileExtent(z, x, y, float64(opts.Size)), GridExtent)
+
+	if level != 0 && e.Xmax > e.Xmin && e.Ymax > e.Ymin {
+		toTile := func(east, north float64) (float64, float64) {
+			ll := nationalgrid.EastingNorthing{Easting: east, Northing: north}.ToWGS84()
+
+			return tilePixel(z, x, y, ll.Lon, ll.Lat, MVTExtent)
+		}
+
+		margin := (e.Xmax - e.Xmin) / 10
+		pad := nationalgrid.Bounds{
+			Xmin: e.Xmin - margin,
+			Ymin: e.Ymin - margin,
+			Xmax: e.Xmax + margin,
+			Ymax: e.Ymax + margin,
+		}
+
+		// coarsest first
+		levels := gridLevels(level)
+		for i := len(levels) - 1; i >= 0; i-- {
+			size := levels[i]
+			if !tileLevel(opts.Levels, size) {
+				continue
+			}
+
+			refs, err := nationalgrid.GridRefsInBounds(e, size)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ref := range refs {
+				b, err := ref.Bounds()
+				if err != nil {
+					return nil, err
+				}
+
+				tags := []uint32{
+					cells.key("ref"), cells.stringValue(ref.String()),
+					cells.key("precision"), cells.doubleValue(size),
+					cells.key("level"), cells.stringValue(nationalgrid.FormatPrecision(size)),
+				}
+
+				ring := mvtRing(clipBounds(b, pad).DensifiedRing(opts.Densify), toTile)
+				if len(ring) >= 3 {
+					cells.addFeature(mvtPolygon, tags, mvtPolygonGeometry(ring))
+				}
+
+				// label only the tile holding the centre, so labels aren't repeated
+				px, py := toTile(b.Xmin+(b.Xmax-b.Xmin)/2, b.Ymin+(b.Ymax-b.Ymin)/2)
+				if px < 0 || py < 0 || px >= MVTExtent || py >= MVTExtent {
+					continue
+				}
+
+				labels.addFeature(mvtPoint, []uint32{
+					labels.key("ref"), labels.stringValue(ref.String()),
+					labels.key("precision"), labels.doubleValue(size),
+					labels.key("level"), labels.stringValue(nationalgrid.FormatPrecision(size)),
+				}, mvtPointGeometry(int32(math.Round(px)), int32(math.Round(py))))
+			}
+		}
+	}
+
+	var tile []byte
+	tile = appendBytesField(tile, mvtTileLayers, cells.encode())
+	tile = appendBytesField(tile, mvtTileLayers, labels.encode())
+
+	return tile, nil
+}
+
+// NewVectorTileHandler serves Mapbox Vector Tiles at /{z}/{x}/{y}.mvt, with
+// the same caching headers as NewTileHandler.
+func NewVectorTileHandler(opts TileOptions) http.Handler {
+	return tileHandler(opts.CacheMaxAge, ".mvt", "application/vnd.mapbox-vector-tile", func(z, x, y int) ([]byte, error) {
+		return VectorTile(z, x, y, opts)
+	})
+}
+
+type mvtLayer struct {
+	name     string
+	features [][]byte
+	keys     []string
+	keyIndex map[string]uint32
+	values   [][]byte
+	valIndex map[string]uint32
+}
+
+func newMVTLayer(name string) *mvtLayer {
+	return &mvtLayer{
+		name:     name,
+		keyIndex: map[string]uint32{},
+		valIndex: map[string]uint32{},
+	}
+}
+
+func (l *mvtLayer) key(k string) uint32 {
+	i, ok := l.keyIndex[k]
+	if !ok {
+		i = uint32(len(l.keys))
+		l.keys = append(l.keys, k)
+		l.keyIndex[k] = i
+	}
+
+	return i
+}
+
+func (l *mvtLayer) value(v []byte) uint32 {
+	i, ok := l.valIndex[string(v)]
+	if !ok {
+		i = uint32(len(l.values))
+		l.values = append(l.values, v)
+		l.valIndex[string(v)] = i
+	}
+
+	return i
+}
+
+func (l *mvtLayer) stringValue(s string) uint32 {
+	return l.value(appendBytesField(nil, mvtValueString, []byte(s)))
+}
+
+func (l *mvtLayer) doubleValue(f float64) uint32 {
+	v := appendTag(nil, mvtValueDouble, wireFixed)
+	v = appendUint64(v, math.Float64bits(f))
+
+	return l.value(v)
+}
+
+// addFeature adds a feature, ids counting from 1 within the layer.
+func (l *mvtLayer) addFeature(geomType uint32, tags, geometry []uint32) {
+	var f []byte
+	f = appendTag(f, mvtFeatureID, wireVarint)
+	f = appendVarint(f, uint64(len(l.features)+1))
+	f = appendBytesField(f, mvtFeatureTags, packUint32s(tags))
+	f = appendTag(f, mvtFeatureType, wireVarint)
+	f = appendVarint(f, uint64(geomType))
+	f = appendBytesField(f, mvtFeatureGeometry, packUint32s(geometry))
+
+	l.features = append(l.features, f)
+}
+
+func (l *mvtLayer) encode() []byte {
+	var b []byte
+	b = appendTag(b, mvtLayerVersion, wireVarint)
+	b = appendVarint(b, 2)
+	b = appendBytesField(b, mvtLayerName, []byte(l.name))
+
+	for _, f := range l.features {
+		b = appendBytesField(b, mvtLayerFeatures, f)
+	}
+
+	for _, k := range l.keys {
+		b = appendBytesField(b, mvtLayerKeys, []byte(k))
+	}
+
+	for _, v := range l.values {
+		b = appendBytesField(b, mvtLayerValues, v)
+	}
+
+	b = appendTag(b, mvtLayerExtent, wireVarint)
+	b = appendVarint(b, MVTExtent)
+
+	return b
+}
+
+// mvtRing projects a closed OSGB36 ring to integer tile coordinates, clipped
+// to the tile buffer, dropping repeated points and the closing point.
+func mvtRing(ring [][2]float64, toTile func(x, y float64) (float64, float64)) [][2]int32 {
+	projected := make([][2]float64, 0, len(ring))
+
+	for _, p := range ring[:len(ring)-1] {
+		x, y := toTile(p[0], p[1])
+		projected = append(projected, [2]float64{x, y})
+	}
+
+	projected = clipRing(projected, -mvtBuffer, MVTExtent+mvtBuffer)
+
+	points := make([][2]int32, 0, len(projected))
+
+	for _, p := range projected {
+		q := [2]int32{int32(math.Round(p[0])), int32(math.Round(p[1]))}
+
+		if len(points) > 0 && points[len(points)-1] == q {
+			continue
+		}
+		points = append(points, q)
+	}
+
+	for len(points) > 1 && points[len(points)-1] == points[0] {
+		points = points[:len(points)-1]
+	}
+
+	// exterior rings are clockwise in tile coordinates, y being down
+	if ringArea(points) < 0 {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+
+	if ringArea(points) == 0 {
+		return nil
+	}
+
+	return points
+}
+
+// clipRing clips an open ring to the square lo, lo to hi, hi with the
+// Sutherland-Hodgman algorithm, one edge of the square at a time.
+func clipRing(ring [][2]float64, lo, hi float64) [][2]float64 {
+	edges := []struct {
+		axis  int
+		v     float64
+		below bool
+	}{
+		{0, lo, false},
+		{0, hi, true},
+		{1, lo, false},
+		{1, hi, true},
+	}
+
+	for _, e := range edges {
+		if len(ring) == 0 {
+			break
+		}
+
+		inside := func(p [2]float64) bool {
+			if e.below {
+				return p[e.axis] <= e.v
+			}
+
+			return p[e.axis] >= e.v
+		}
+
+		crossing := func(a, b [2]float64) [2]float64 {
+			t := (e.v - a[e.axis]) / (b[e.axis] - a[e.axis])
+
+			p := [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+			p[e.axis] = e.v
+
+			return p
+		}
+
+		clipped := make([][2]float64, 0, len(ring)+1)
+		prev := ring[len(ring)-1]
+
+		for _, p := range ring {
+			switch {
+			case inside(p) && !inside(prev):
+				clipped = append(clipped, crossing(prev, p), p)
+			case inside(p):
+				clipped = append(clipped, p)
+			case inside(prev):
+				clipped = append(clipped, crossing(prev, p))
+			}
+			prev = p
+		}
+
+		ring = clipped
+	}
+
+	return ring
+}
+
+// ringArea returns twice the signed area of a ring, positive when clockwise
+// with y down.
+func ringArea(points [][2]int32) int64 {
+	var a int64
+
+	for i := range points {
+		p, q := points[i], points[(i+1)%len(points)]
+		a += int64(p[0])*int64(q[1]) - int64(q[0])*int64(p[1])
+	}
+
+	return a
+}
+
+func mvtPolygonGeometry(ring [][2]int32) []uint32 {
+	g := make([]uint32, 0, 2*len(ring)+3)
+
+	g = append(g, mvtCommand(mvtMoveTo, 1), zigzag(ring[0][0]), zigzag(ring[0][1]))
+	g = append(g, mvtCommand(mvtLineTo, len(ring)-1))
+
+	for i := 1; i < len(ring); i++ {
+		g = append(g, zigzag(ring[i][0]-ring[i-1][0]), zigzag(ring[i][1]-ring[i-1][1]))
+	}
+
+	return append(g, mvtCommand(mvtClosePath, 1))
+}
+
+func mvtPointGeometry(x, y int32) []uint32 {
+	return []uint32{mvtCommand(mvtMoveTo, 1), zigzag(x), zigzag(y)}
+}
+
+func mvtCommand(id, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+func zigzag(v int32) uint32 {
+	return uint32((v << 1) ^ (v >> 31))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendVarint(b, uint64(field<<3|wire))
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+
+	return append(b, v...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+
+	return b
+}
+
+func packUint32s(vs []uint32) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = appendVarint(b, uint64(v))
+	}
+
+	return b
+}
@@ -0,0 +1,273 @@
+package render
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testLayer struct {
+	name     string
+	features []testFeature
+	keys     []string
+	values   []interface{}
+	extent   uint64
+}
+
+type testFeature struct {
+	geomType uint64
+	tags     []uint64
+	geometry []uint64
+}
+
+func TestVectorTile(t *testing.T) {
+	opts := DefaultTileOptions()
+
+	b, err := VectorTile(10, 505, 330, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers := decodeTile(t, b)
+	if len(layers) != 2 || layers[0].name != MVTCellsLayer || layers[1].name != MVTLabelsLayer {
+		t.Fatalf("expected cells and labels layers, got %+v", layers)
+	}
+
+	cells, labels := layers[0], layers[1]
+
+	if cells.extent != MVTExtent {
+		t.Fatalf("expected extent %v, got %v", MVTExtent, cells.extent)
+	}
+
+	refs := map[string]bool{}
+	for _, f := range cells.features {
+		if f.geomType != mvtPolygon {
+			t.Fatalf("expected a polygon, got %v", f.geomType)
+		}
+
+		attrs := featureAttrs(cells, f)
+		refs[attrs["ref"].(string)] = true
+
+		if attrs["level"] == "10km" && attrs["precision"] != 10000.0 {
+			t.Fatalf("expected precision 10000, got %+v", attrs)
+		}
+	}
+
+	for _, ref := range []string{"SD", "SD81", "SD91"} {
+		if !refs[ref] {
+			t.Fatalf("expected a %v cell, got %v", ref, refs)
+		}
+	}
+
+	for _, f := range labels.features {
+		if f.geomType != mvtPoint || len(f.geometry) != 3 {
+			t.Fatalf("expected a point, got %+v", f)
+		}
+
+		x, y := unzigzag(f.geometry[1]), unzigzag(f.geometry[2])
+		if x < 0 || y < 0 || x >= MVTExtent || y >= MVTExtent {
+			t.Fatalf("expected label inside the tile, got %v, %v", x, y)
+		}
+	}
+
+	// off the grid
+	b, err = VectorTile(10, 400, 330, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range decodeTile(t, b) {
+		if len(l.features) != 0 {
+			t.Fatalf("expected no features off the grid, got %v", len(l.features))
+		}
+	}
+}
+
+func TestMVTRingWinding(t *testing.T) {
+	// a counter-clockwise ring with y up stays counter-clockwise on screen
+	// once flipped, so is reversed
+	flip := func(x, y float64) (float64, float64) {
+		return x, MVTExtent - y
+	}
+
+	ring := mvtRing([][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}, flip)
+	if ringArea(ring) <= 0 {
+		t.Fatalf("expected a clockwise ring, got %v", ring)
+	}
+
+	expected := []uint32{9, 0, 8172, 26, 20, 0, 0, 20, 19, 0, 15}
+	actual := mvtPolygonGeometry(ring)
+
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestMVTRingClip(t *testing.T) {
+	identity := func(x, y float64) (float64, float64) {
+		return x, y
+	}
+
+	// straddles the left edge, the long side leaving the buffer at -64, 136
+	ring := mvtRing([][2]float64{{-200, 0}, {100, 0}, {100, 300}, {-200, 0}}, identity)
+
+	expected := [][2]int32{{-64, 136}, {-64, 0}, {100, 0}, {100, 300}}
+	if fmt.Sprint(expected) != fmt.Sprint(ring) {
+		t.Fatalf("expected %v, got %v", expected, ring)
+	}
+
+	// outside the buffer altogether
+	ring = mvtRing([][2]float64{{-300, 0}, {-100, 0}, {-100, 300}, {-300, 0}}, identity)
+	if ring != nil {
+		t.Fatalf("expected no ring, got %v", ring)
+	}
+}
+
+func TestVectorTileHandler(t *testing.T) {
+	h := NewVectorTileHandler(DefaultTileOptions())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/10/505/330.mvt", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v %v", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	if rec.Header().Get("Content-Type") != "application/vnd.mapbox-vector-tile" {
+		t.Fatalf("unexpected Content-Type %v", rec.Header().Get("Content-Type"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/10/505/330.png", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected %v, got %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+func featureAttrs(l testLayer, f testFeature) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	for i := 0; i+1 < len(f.tags); i += 2 {
+		attrs[l.keys[f.tags[i]]] = l.values[f.tags[i+1]]
+	}
+
+	return attrs
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// decodeTile reads the parts of a vector tile written by VectorTile.
+func decodeTile(t *testing.T, b []byte) []testLayer {
+	t.Helper()
+
+	var layers []testLayer
+
+	readFields(t, b, func(field int, v uint64, data []byte) {
+		if field != mvtTileLayers {
+			t.Fatalf("unexpected tile field %v", field)
+		}
+
+		var l testLayer
+		readFields(t, data, func(field int, v uint64, data []byte) {
+			switch field {
+			case mvtLayerName:
+				l.name = string(data)
+			case mvtLayerKeys:
+				l.keys = append(l.keys, string(data))
+			case mvtLayerExtent:
+				l.extent = v
+			case mvtLayerValues:
+				readFields(t, data, func(field int, v uint64, data []byte) {
+					switch field {
+					case mvtValueString:
+						l.values = append(l.values, string(data))
+					case mvtValueDouble:
+						l.values = append(l.values, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+					}
+				})
+			case mvtLayerFeatures:
+				var f testFeature
+				readFields(t, data, func(field int, v uint64, data []byte) {
+					switch field {
+					case mvtFeatureType:
+						f.geomType = v
+					case mvtFeatureTags:
+						f.tags = readPacked(t, data)
+					case mvtFeatureGeometry:
+						f.geometry = readPacked(t, data)
+					}
+				})
+				l.features = append(l.features, f)
+			}
+		})
+
+		layers = append(layers, l)
+	})
+
+	return layers
+}
+
+func readFields(t *testing.T, b []byte, fn func(field int, v uint64, data []byte)) {
+	t.Helper()
+
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("invalid varint")
+		}
+		b = b[n:]
+
+		field := int(key >> 3)
+
+		switch key & 0x7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatal("invalid varint")
+			}
+			b = b[n:]
+			fn(field, v, nil)
+		case wireFixed:
+			fn(field, 0, b[:8])
+			b = b[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatal("invalid length")
+			}
+			b = b[n:]
+			fn(field, 0, b[:l])
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %v", key&0x7)
+		}
+	}
+}
+
+func readPacked(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+
+	var vs []uint64
+
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("invalid packed varint")
+		}
+		vs = append(vs, v)
+		b = b[n:]
+	}
+
+	return vs
+}
@@ -0,0 +1,301 @@
+// Package render draws National Grid overlays to images.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/rockwell-uk/go-geos-draw/geom"
+	"github.com/rockwell-uk/go-text/fonts"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+var (
+	black = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+// LabelFunc returns the label drawn at the centre of a cell, "" for none.
+type LabelFunc func(ref nationalgrid.GridRef) string
+
+// LabelRef labels cells with their full grid ref, e.g. "SD91NW".
+func LabelRef(ref nationalgrid.GridRef) string {
+	return ref.String()
+}
+
+// LabelLocal labels cells with the part of their ref within the parent cell,
+// e.g. "SD" for a square, "91" for a subsquare, "NW" for a quadrant and the
+// last easting and northing digits of finer cells.
+func LabelLocal(ref nationalgrid.GridRef) string {
+	switch {
+	case ref.Quadrant != "":
+		return ref.Quadrant.String()
+	case ref.SubSquare != "":
+		k := len(ref.SubSquare) / 2
+		return ref.SubSquare[k-1:k] + ref.SubSquare[2*k-1:]
+	}
+
+	return ref.Square
+}
+
+// Style is how the cells of one level of the grid are drawn. Lines are drawn
+// in LineColor over a casing of StrokeColor StrokeWidth wider.
+type Style struct {
+	LineWidth   float64
+	LineColor   color.RGBA
+	StrokeWidth float64
+	StrokeColor color.RGBA
+	FontData    draw2d.FontData
+	FontSize    float64
+	FontColor   color.RGBA
+	Label       LabelFunc
+}
+
+type Options struct {
+	Width  int
+	Height int
+	// Extent is the OSGB36 area drawn, stretched to the image size.
+	Extent nationalgrid.Bounds
+	// Precision is the finest level drawn, every coarser level with a
+	// style is drawn over it.
+	Precision  float64
+	Background color.RGBA
+	// Styles are keyed by cell size, one of the nationalgrid Precisions.
+	Styles map[float64]Style
+}
+
+var DefaultStyles = map[float64]Style{
+	nationalgrid.SquareSize: {
+		LineWidth:   2,
+		LineColor:   black,
+		StrokeColor: black,
+		FontData: draw2d.FontData{
+			Name:   "bold",
+			Family: draw2d.FontFamilySans,
+			Style:  draw2d.FontStyleNormal,
+		},
+		FontSize:  30,
+		FontColor: black,
+		Label:     LabelLocal,
+	},
+	nationalgrid.SubSquareSize: {
+		LineWidth:   1,
+		LineColor:   black,
+		StrokeColor: black,
+		FontData: draw2d.FontData{
+			Name:   "bold",
+			Family: draw2d.FontFamilySans,
+			Style:  draw2d.FontStyleNormal,
+		},
+		FontSize:  10,
+		FontColor: black,
+		Label:     LabelLocal,
+	},
+	nationalgrid.QuadrantSize: {
+		LineWidth:   0.5,
+		LineColor:   black,
+		StrokeColor: black,
+		FontData: draw2d.FontData{
+			Name:   "regular",
+			Family: draw2d.FontFamilySans,
+			Style:  draw2d.FontStyleNormal,
+		},
+		FontSize:  8,
+		FontColor: black,
+	},
+}
+
+// GridExtent is the whole of the National Grid.
+var GridExtent = nationalgrid.Bounds{
+	Xmin: 0,
+	Xmax: 7 * nationalgrid.SquareSize,
+	Ymin: 0,
+	Ymax: 13 * nationalgrid.SquareSize,
+}
+
+// DefaultOptions draws the 100km squares of the whole grid at 1px per km.
+func DefaultOptions() Options {
+	return Options{
+		Width:      700,
+		Height:     1300,
+		Extent:     GridExtent,
+		Precision:  nationalgrid.SquareSize,
+		Background: white,
+		Styles:     DefaultStyles,
+	}
+}
+
+// Grid draws the grid cells within the extent, finest level first so that
+// coarser lines and labels are drawn on top.
+func Grid(opts Options) (image.Image, error) {
+	m := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(m, m.Bounds(), &image.Uniform{opts.Background}, image.Point{}, draw.Src)
+
+	gc := draw2dimg.NewGraphicContext(m)
+	gc.SetDPI(72)
+
+	err := DrawGrid(gc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// DrawGrid draws the grid cells within the extent onto an existing graphic
+// context, the context being opts.Width by opts.Height pixels.
+func DrawGrid(gc *draw2dimg.GraphicContext, opts Options) error {
+	e := opts.Extent
+
+	toPixel, err := projection(opts.Width, opts.Height, e)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range levels(opts) {
+		style := opts.Styles[size]
+
+		refs, err := nationalgrid.GridRefsInBounds(e, size)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range refs {
+			err := drawCell(gc, ref, style, toPixel)
+			if err != nil {
+				return err
+			}
+		}
+
+		if style.Label == nil {
+			continue
+		}
+
+		typeFace, fm, err := setLabelFont(gc, style)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range refs {
+			label := style.Label(ref)
+			if label == "" {
+				continue
+			}
+
+			b, err := ref.Bounds()
+			if err != nil {
+				return err
+			}
+
+			x, y := toPixel(b.Xmin+(b.Xmax-b.Xmin)/2, b.Ymin+(b.Ymax-b.Ymin)/2)
+			textWidth := fonts.GetTextWidth(typeFace, label)
+
+			labelPos := []float64{
+				x - textWidth/2,
+				y + ((fm.Ascent - fm.Descent) / 2),
+			}
+
+			err = geom.DrawString(gc, labelPos, 0, label)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// projection returns a function from OSGB36 to pixels, y down, stretching
+// the extent to the image size.
+func projection(width, height int, e nationalgrid.Bounds) (func(x, y float64) (float64, float64), error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid image size %vx%v", width, height)
+	}
+
+	if e.Xmax <= e.Xmin || e.Ymax <= e.Ymin {
+		return nil, fmt.Errorf("invalid extent %+v", e)
+	}
+
+	sx := float64(width) / (e.Xmax - e.Xmin)
+	sy := float64(height) / (e.Ymax - e.Ymin)
+
+	return func(x, y float64) (float64, float64) {
+		return (x - e.Xmin) * sx, float64(height) - (y-e.Ymin)*sy
+	}, nil
+}
+
+// levels returns the cell sizes to draw, finest first.
+func levels(opts Options) []float64 {
+	var sizes []float64
+
+	for _, size := range gridLevels(opts.Precision) {
+		if _, ok := opts.Styles[size]; ok {
+			sizes = append(sizes, size)
+		}
+	}
+
+	return sizes
+}
+
+// gridLevels returns the Precisions no finer than precision, finest first,
+// along with precision itself when it is a quadrant size such as 500m.
+func gridLevels(precision float64) []float64 {
+	sizes := []float64{precision}
+
+	for _, size := range nationalgrid.Precisions {
+		if size > precision {
+			sizes = append(sizes, size)
+		}
+	}
+
+	sort.Float64s(sizes)
+
+	return sizes
+}
+
+func drawCell(gc *draw2dimg.GraphicContext, ref nationalgrid.GridRef, style Style, toPixel func(x, y float64) (float64, float64)) error {
+	b, err := ref.Bounds()
+	if err != nil {
+		return err
+	}
+
+	ring := [][]float64{
+		{b.Xmin, b.Ymin},
+		{b.Xmax, b.Ymin},
+		{b.Xmax, b.Ymax},
+		{b.Xmin, b.Ymax},
+		{b.Xmin, b.Ymin},
+	}
+
+	return geom.DrawCoordLine(gc, ring, style.LineWidth, style.LineColor, style.StrokeWidth, style.StrokeColor, toPixel)
+}
+
+// setLabelFont loads the style's font and makes it the current font of gc,
+// loading first as fonts.GetFace panics on fonts missing from the cache.
+func setLabelFont(gc *draw2dimg.GraphicContext, style Style) (fonts.TypeFace, fonts.FaceMetrics, error) {
+	_, err := gc.FontCache.Load(style.FontData)
+	if err != nil {
+		return fonts.TypeFace{}, fonts.FaceMetrics{}, err
+	}
+
+	typeFace := fonts.TypeFace{
+		Name: style.FontData.Name,
+		StrokeStyle: draw2d.StrokeStyle{
+			Color: white,
+			Width: 1,
+		},
+		Color:    style.FontColor,
+		Size:     style.FontSize,
+		FontData: style.FontData,
+		Face:     fonts.GetFace(gc, style.FontData, style.FontSize),
+	}
+	fonts.SetFont(gc, typeFace)
+
+	return typeFace, fonts.GetFaceMetrics(typeFace), nil
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dimg"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func TestDrawSquares(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Styles = map[float64]Style{
+		nationalgrid.SquareSize: DefaultStyles[nationalgrid.SubSquareSize],
+	}
+
+	m, err := Grid(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Bounds() != image.Rect(0, 0, 700, 1300) {
+		t.Fatalf("unexpected image bounds %v", m.Bounds())
+	}
+
+	// the line between SV and SW at x = 100km, and the middle of the empty
+	// sea in the top left corner
+	assertDark(t, m, 100, 1250)
+	assertLight(t, m, 50, 50)
+
+	err = savePNG("../test-output/all.png", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrawSubSectors(t *testing.T) {
+	sv, err := nationalgrid.GridRef{Square: "SV"}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Width = 1000
+	opts.Height = 1000
+	opts.Extent = sv
+	opts.Precision = nationalgrid.SubSquareSize
+
+	m, err := Grid(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the line between SV00 and SV10, and the middle of SV00 right of its label
+	assertDark(t, m, 100, 950)
+	assertLight(t, m, 80, 950)
+
+	err = savePNG("../test-output/subsectors.png", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrawQuadrantsLabelRef(t *testing.T) {
+	b, err := nationalgrid.GridRef{Square: "SD", SubSquare: "91"}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Width = 200
+	opts.Height = 200
+	opts.Extent = b
+	opts.Precision = nationalgrid.QuadrantSize
+	opts.Styles = map[float64]Style{
+		nationalgrid.QuadrantSize: {
+			LineWidth: 1,
+			LineColor: color.RGBA{0xFF, 0x00, 0x00, 0xFF},
+			FontData:  draw2d.FontData{Name: "regular"},
+			FontSize:  8,
+			FontColor: color.RGBA{0x00, 0x00, 0xFF, 0xFF},
+			Label:     LabelRef,
+		},
+	}
+
+	m, err := Grid(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the quadrant line through the middle is red
+	r, g, _, _ := m.At(100, 20).RGBA()
+	if r < 0x8000 || g > 0x8000 {
+		t.Fatalf("expected a red line at 100, 20, got %v", m.At(100, 20))
+	}
+}
+
+func TestGridErrors(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Width = 0
+
+	_, err := Grid(opts)
+	if err == nil {
+		t.Fatal("expected error for empty image")
+	}
+
+	opts = DefaultOptions()
+	opts.Extent = nationalgrid.Bounds{}
+
+	_, err = Grid(opts)
+	if err == nil {
+		t.Fatal("expected error for empty extent")
+	}
+
+	opts = DefaultOptions()
+	style := DefaultStyles[nationalgrid.SquareSize]
+	style.FontData = draw2d.FontData{Name: "missing"}
+	opts.Styles = map[float64]Style{
+		nationalgrid.SquareSize: style,
+	}
+
+	_, err = Grid(opts)
+	if err == nil {
+		t.Fatal("expected error for missing font")
+	}
+}
+
+func TestLabelLocal(t *testing.T) {
+	tests := map[string]nationalgrid.GridRef{
+		"SD": {Square: "SD"},
+		"91": {Square: "SD", SubSquare: "91"},
+		"NW": {Square: "SD", SubSquare: "91", Quadrant: nationalgrid.NW},
+	}
+
+	for expected, ref := range tests {
+		actual := LabelLocal(ref)
+		if expected != actual {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+}
+
+func assertDark(t *testing.T, m image.Image, x, y int) {
+	t.Helper()
+
+	r, g, b, _ := m.At(x, y).RGBA()
+	if r+g+b > 3*0xC000 {
+		t.Fatalf("expected a dark pixel at %v, %v, got %v", x, y, m.At(x, y))
+	}
+}
+
+func assertLight(t *testing.T, m image.Image, x, y int) {
+	t.Helper()
+
+	r, g, b, _ := m.At(x, y).RGBA()
+	if r+g+b < 3*0xF000 {
+		t.Fatalf("expected a light pixel at %v, %v, got %v", x, y, m.At(x, y))
+	}
+}
+
+func savePNG(fname string, m image.Image) error {
+	dir, _ := path.Split(fname)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return draw2dimg.SaveToPngFile(fname, m)
+}
@@ -0,0 +1,246 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+// DefaultSVGCSS styles each level of the grid by its "level-<precision>"
+// class, e.g. "level-10km", and overlays by their "overlay" class.
+const DefaultSVGCSS = `.cell { fill: none; stroke: #000; }
+.label { font-family: sans-serif; text-anchor: middle; dominant-baseline: central; }
+.level-100km .cell { stroke-width: 2; }
+.level-100km .label { font-size: 30px; font-weight: bold; }
+.level-10km .cell { stroke-width: 1; }
+.level-10km .label { font-size: 10px; font-weight: bold; }
+.level-5km .cell { stroke-width: 0.5; }
+.level-5km .label { font-size: 8px; }
+.level-1km .cell, .level-100m .cell, .level-10m .cell, .level-1m .cell { stroke-width: 0.25; }
+.level-1km .label, .level-100m .label, .level-10m .label, .level-1m .label { font-size: 6px; }
+.overlay { fill: none; stroke: #c00; stroke-width: 2; }
+`
+
+// Overlay is a user geometry drawn over the grid.
+type Overlay struct {
+	// Class is added to the "overlay" class of the path.
+	Class string
+	// Coords are OSGB36 easting / northing pairs.
+	Coords [][]float64
+	// Closed closes the path into a polygon.
+	Closed bool
+}
+
+type SVGOptions struct {
+	Width  int
+	Height int
+	// Extent is the OSGB36 area drawn, stretched to the image size.
+	Extent nationalgrid.Bounds
+	// Precision is the finest level drawn, every coarser level is drawn over it.
+	Precision float64
+	// Labels are keyed by cell size, levels without a LabelFunc are unlabelled.
+	Labels map[float64]LabelFunc
+	// CSS is embedded in a style element, "" for none.
+	CSS      string
+	Overlays []Overlay
+}
+
+// DefaultSVGOptions draws the labelled 100km squares of the whole grid at 1px per km.
+func DefaultSVGOptions() SVGOptions {
+	return SVGOptions{
+		Width:     700,
+		Height:    1300,
+		Extent:    GridExtent,
+		Precision: nationalgrid.SquareSize,
+		Labels: map[float64]LabelFunc{
+			nationalgrid.SquareSize:    LabelLocal,
+			nationalgrid.SubSquareSize: LabelLocal,
+		},
+		CSS: DefaultSVGCSS,
+	}
+}
+
+// WriteSVG writes the grid cells within the extent as an SVG document, a
+// group per level finest first, then the overlays. Cells are written in
+// GridRefsInBounds order and coordinates are rounded to 2 decimal places
+// so the same options always give the same bytes.
+func WriteSVG(w io.Writer, opts SVGOptions) error {
+	toPixel, err := projection(opts.Width, opts.Height, opts.Extent)
+	if err != nil {
+		return err
+	}
+
+	err = nationalgrid.ValidatePrecision(opts.Precision)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	writeSVGHeader(bw, opts)
+
+	err = writeSVGLevels(bw, opts, toPixel)
+	if err != nil {
+		return err
+	}
+
+	err = writeSVGOverlays(bw, opts.Overlays, toPixel)
+	if err != nil {
+		return err
+	}
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
+
+func writeSVGHeader(bw *bufio.Writer, opts SVGOptions) {
+	fmt.Fprintf(bw, "<svg xmlns=%q width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", svgNamespace, opts.Width, opts.Height, opts.Width, opts.Height)
+
+	if opts.CSS != "" {
+		bw.WriteString("<style>\n")
+		svgEscape(bw, opts.CSS)
+		bw.WriteString("</style>\n")
+	}
+}
+
+// writeSVGLevels writes a group of cells per level, finest first.
+func writeSVGLevels(bw *bufio.Writer, opts SVGOptions, toPixel func(x, y float64) (float64, float64)) error {
+	for _, size := range gridLevels(opts.Precision) {
+		refs, err := nationalgrid.GridRefsInBounds(opts.Extent, size)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, "<g class=\"level-%s\">\n", nationalgrid.FormatPrecision(size))
+
+		label := opts.Labels[size]
+
+		for _, ref := range refs {
+			x, y, width, height, err := svgCell(ref, toPixel)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(bw, "<rect class=\"cell\" data-ref=\"%s\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"/>\n",
+				ref, formatNumber(x), formatNumber(y), formatNumber(width), formatNumber(height))
+
+			if label == nil {
+				continue
+			}
+
+			writeSVGLabel(bw, "label", label(ref), x+width/2, y+height/2)
+		}
+
+		bw.WriteString("</g>\n")
+	}
+
+	return nil
+}
+
+func writeSVGOverlays(bw *bufio.Writer, overlays []Overlay, toPixel func(x, y float64) (float64, float64)) error {
+	if len(overlays) == 0 {
+		return nil
+	}
+
+	bw.WriteString("<g class=\"overlays\">\n")
+
+	for _, o := range overlays {
+		d, err := svgPath(o, toPixel)
+		if err != nil {
+			return err
+		}
+
+		class := "overlay"
+		if o.Class != "" {
+			class += " " + o.Class
+		}
+
+		bw.WriteString("<path class=\"")
+		svgEscape(bw, class)
+		fmt.Fprintf(bw, "\" d=\"%s\"/>\n", d)
+	}
+
+	bw.WriteString("</g>\n")
+
+	return nil
+}
+
+// svgCell returns the top left corner and size of a cell in pixels.
+func svgCell(ref nationalgrid.GridRef, toPixel func(x, y float64) (float64, float64)) (float64, float64, float64, float64, error) {
+	b, err := ref.Bounds()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	x0, y0 := toPixel(b.Xmin, b.Ymax)
+	x1, y1 := toPixel(b.Xmax, b.Ymin)
+
+	return x0, y0, x1 - x0, y1 - y0, nil
+}
+
+func writeSVGLabel(bw *bufio.Writer, class, text string, x, y float64) {
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(bw, "<text class=\"%s\" x=\"%s\" y=\"%s\">", class, formatNumber(x), formatNumber(y))
+	svgEscape(bw, text)
+	bw.WriteString("</text>\n")
+}
+
+func svgPath(o Overlay, toPixel func(x, y float64) (float64, float64)) (string, error) {
+	if len(o.Coords) == 0 {
+		return "", fmt.Errorf("overlay has no coordinates")
+	}
+
+	var sb strings.Builder
+
+	for i, c := range o.Coords {
+		if len(c) < 2 {
+			return "", fmt.Errorf("invalid overlay coordinate %v", c)
+		}
+
+		x, y := toPixel(c[0], c[1])
+
+		if i == 0 {
+			sb.WriteString("M")
+		} else {
+			sb.WriteString(" L")
+		}
+		sb.WriteString(formatNumber(x) + " " + formatNumber(y))
+	}
+
+	if o.Closed {
+		sb.WriteString(" Z")
+	}
+
+	return sb.String(), nil
+}
+
+// formatNumber formats a value to at most 2 decimal places.
+func formatNumber(v float64) string {
+	v = math.Round(v*100) / 100
+	if v == 0 {
+		// no "-0"
+		v = 0
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// svgEscape escapes text and attribute values, leaving newlines so that
+// embedded css stays readable.
+func svgEscape(w *bufio.Writer, s string) {
+	// writes to a bufio.Writer only fail on Flush
+	_, _ = svgEscaper.WriteString(w, s)
+}
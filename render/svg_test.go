@@ -0,0 +1,100 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestWriteSVG(t *testing.T) {
+	b, err := nationalgrid.GridRef{Square: "SD", SubSquare: "91"}.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultSVGOptions()
+	opts.Width = 200
+	opts.Height = 200
+	opts.Extent = b
+	opts.Precision = nationalgrid.KmSquareSize
+	opts.Labels = map[float64]LabelFunc{
+		nationalgrid.SubSquareSize: LabelRef,
+		nationalgrid.QuadrantSize:  LabelLocal,
+	}
+	opts.Overlays = []Overlay{
+		{
+			Class: "route",
+			Coords: [][]float64{
+				{391500, 411500},
+				{398500, 418500},
+			},
+		},
+		{
+			Coords: [][]float64{
+				{392000, 412000},
+				{394000, 412000},
+				{393000, 414000},
+			},
+			Closed: true,
+		},
+	}
+
+	var buf bytes.Buffer
+
+	err = WriteSVG(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := "testdata/sd91.svg"
+
+	if *update {
+		err = os.WriteFile(golden, buf.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("output differs from %v, run go test -update to regenerate\n%s", golden, buf.Bytes())
+	}
+}
+
+func TestWriteSVGErrors(t *testing.T) {
+	tests := map[string]func(*SVGOptions){
+		"size": func(o *SVGOptions) {
+			o.Width = 0
+		},
+		"extent": func(o *SVGOptions) {
+			o.Extent = nationalgrid.Bounds{}
+		},
+		"precision": func(o *SVGOptions) {
+			o.Precision = 2000
+		},
+		"overlay": func(o *SVGOptions) {
+			o.Overlays = []Overlay{{Coords: [][]float64{{1}}}}
+		},
+	}
+
+	for name, modify := range tests {
+		opts := DefaultSVGOptions()
+		modify(&opts)
+
+		var buf bytes.Buffer
+
+		err := WriteSVG(&buf, opts)
+		if err == nil {
+			t.Fatalf("%v expected error", name)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // etags only
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/rockwell-uk/go-geos-draw/geom"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+const (
+	earthRadius = 6378137.0
+	// maxLat is the latitude at which web mercator tiles are square.
+	maxLat = 85.0511287798066
+)
+
+type TileOptions struct {
+	// Size is the width and height of a tile in pixels.
+	Size int
+	// MaxZoom is the deepest zoom level served.
+	MaxZoom int
+	// Levels are the cell sizes that may be drawn, each one of the
+	// nationalgrid Precisions.
+	Levels []float64
+	// MinCellPixels is the smallest a cell is drawn, the finest level at
+	// least this many pixels across being drawn with every coarser level
+	// over it.
+	MinCellPixels float64
+	// Densify is the number of points inserted along each cell edge so the
+	// curved grid lines are followed once reprojected.
+	Densify int
+	Styles  map[float64]Style
+	// CacheMaxAge is the max-age of the Cache-Control header in seconds.
+	CacheMaxAge int
+}
+
+// DefaultTileStyles draws the grid in dark blue, labelled with full refs.
+var DefaultTileStyles = map[float64]Style{
+	nationalgrid.SquareSize:    tileStyle(2.5, "bold", 16),
+	nationalgrid.SubSquareSize: tileStyle(1.5, "bold", 12),
+	nationalgrid.KmSquareSize:  tileStyle(1, "regular", 10),
+	nationalgrid.HectareSize:   tileStyle(0.75, "regular", 10),
+	nationalgrid.TenMetreSize:  tileStyle(0.5, "regular", 9),
+}
+
+func tileStyle(lineWidth float64, font string, fontSize float64) Style {
+	blue := color.RGBA{0x00, 0x33, 0x99, 0xFF}
+
+	return Style{
+		LineWidth:   lineWidth,
+		LineColor:   blue,
+		StrokeColor: blue,
+		FontData: draw2d.FontData{
+			Name:   font,
+			Family: draw2d.FontFamilySans,
+			Style:  draw2d.FontStyleNormal,
+		},
+		FontSize:  fontSize,
+		FontColor: blue,
+		Label:     LabelRef,
+	}
+}
+
+// DefaultTileOptions serves 256px tiles to zoom 22, drawing the 100km grid at
+// low zooms down to the 10m grid at high zooms.
+func DefaultTileOptions() TileOptions {
+	return TileOptions{
+		Size:    256,
+		MaxZoom: 22,
+		Levels: []float64{
+			nationalgrid.SquareSize,
+			nationalgrid.SubSquareSize,
+			nationalgrid.KmSquareSize,
+			nationalgrid.HectareSize,
+			nationalgrid.TenMetreSize,
+		},
+		MinCellPixels: 96,
+		Densify:       8,
+		Styles:        DefaultTileStyles,
+		CacheMaxAge:   7 * 24 * 60 * 60,
+	}
+}
+
+// TileLonLat returns the WGS84 lon / lat of a pixel of tile z/x/y.
+func TileLonLat(z, x, y int, px, py, size float64) (float64, float64) {
+	world := size * math.Exp2(float64(z))
+
+	mx := float64(x)*size + px
+	my := float64(y)*size + py
+
+	lon := mx/world*360 - 180
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*my/world))) * 180 / math.Pi
+
+	return lon, lat
+}
+
+// tilePixel returns the pixel of tile z/x/y at a WGS84 lon / lat.
+func tilePixel(z, x, y int, lon, lat, size float64) (float64, float64) {
+	world := size * math.Exp2(float64(z))
+
+	lat = math.Max(-maxLat, math.Min(maxLat, lat))
+	rad := lat * math.Pi / 180
+
+	mx := (lon + 180) / 360 * world
+	my := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * world
+
+	return mx - float64(x)*size, my - float64(y)*size
+}
+
+// TileLevel returns the finest of opts.Levels whose cells are at least
+// opts.MinCellPixels across at the middle of tile z/x/y, 0 if none are.
+func TileLevel(z, x, y int, opts TileOptions) float64 {
+	size := float64(opts.Size)
+	_, lat := TileLonLat(z, x, y, size/2, size/2, size)
+
+	metresPerPixel := 2 * math.Pi * earthRadius * math.Cos(lat*math.Pi/180) / (size * math.Exp2(float64(z)))
+
+	level := 0.0
+	for _, l := range opts.Levels {
+		if l/metresPerPixel < opts.MinCellPixels {
+			continue
+		}
+		if level == 0 || l < level {
+			level = l
+		}
+	}
+
+	return level
+}
+
+// tileExtent returns the OSGB36 bounds of tile z/x/y, sampled along its
+// edges since the tile is not square on the National Grid.
+func tileExtent(z, x, y int, size float64) nationalgrid.Bounds {
+	const samples = 8
+
+	b := nationalgrid.Bounds{
+		Xmin: math.Inf(1),
+		Ymin: math.Inf(1),
+		Xmax: math.Inf(-1),
+		Ymax: math.Inf(-1),
+	}
+
+	for i := 0; i <= samples; i++ {
+		f := size * float64(i) / samples
+
+		for _, p := range [][2]float64{{f, 0}, {f, size}, {0, f}, {size, f}} {
+			lon, lat := TileLonLat(z, x, y, p[0], p[1], size)
+			en := nationalgrid.LatLon{Lat: lat, Lon: lon}.ToEastingNorthing()
+			if math.IsNaN(en.Easting) || math.IsNaN(en.Northing) {
+				continue
+			}
+
+			b.Xmin = math.Min(b.Xmin, en.Easting)
+			b.Ymin = math.Min(b.Ymin, en.Northing)
+			b.Xmax = math.Max(b.Xmax, en.Easting)
+			b.Ymax = math.Max(b.Ymax, en.Northing)
+		}
+	}
+
+	return b
+}
+
+// Tile draws the grid onto a transparent web mercator tile z/x/y, the grid
+// lines being reprojected from OSGB36.
+func Tile(z, x, y int, opts TileOptions) (image.Image, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("invalid tile size %v", opts.Size)
+	}
+
+	err := validateTile(z, x, y, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	size := float64(opts.Size)
+	m := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+
+	level := TileLevel(z, x, y, opts)
+	if level == 0 {
+		return m, nil
+	}
+
+	e := tileExtent(z, x, y, size)
+
+	// clip to the grid, leaving tiles off it empty
+	e = clipBounds(e, GridExtent)
+
+	if e.Xmax <= e.Xmin || e.Ymax <= e.Ymin {
+		return m, nil
+	}
+
+	toPixel := func(east, north float64) (float64, float64) {
+		ll := nationalgrid.EastingNorthing{Easting: east, Northing: north}.ToWGS84()
+
+		return tilePixel(z, x, y, ll.Lon, ll.Lat, size)
+	}
+
+	gc := draw2dimg.NewGraphicContext(m)
+	gc.SetDPI(72)
+
+	for _, l := range gridLevels(level) {
+		style, ok := opts.Styles[l]
+		if !ok || !tileLevel(opts.Levels, l) {
+			continue
+		}
+
+		err := drawTileLevel(gc, e, l, style, opts, toPixel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func validateTile(z, x, y int, opts TileOptions) error {
+	if z < 0 || z > opts.MaxZoom {
+		return fmt.Errorf("%w %v/%v/%v", errInvalidTile, z, x, y)
+	}
+
+	n := 1 << uint(z)
+	if x < 0 || x >= n || y < 0 || y >= n {
+		return fmt.Errorf("%w %v/%v/%v", errInvalidTile, z, x, y)
+	}
+
+	return nil
+}
+
+func tileLevel(levels []float64, l float64) bool {
+	for _, level := range levels {
+		if level == l {
+			return true
+		}
+	}
+
+	return false
+}
+
+func drawTileLevel(gc *draw2dimg.GraphicContext, e nationalgrid.Bounds, size float64, style Style, opts TileOptions, toPixel func(x, y float64) (float64, float64)) error {
+	refs, err := nationalgrid.GridRefsInBounds(e, size)
+	if err != nil {
+		return err
+	}
+
+	margin := (e.Xmax - e.Xmin) / 10
+	pad := nationalgrid.Bounds{
+		Xmin: e.Xmin - margin,
+		Ymin: e.Ymin - margin,
+		Xmax: e.Xmax + margin,
+		Ymax: e.Ymax + margin,
+	}
+
+	for _, ref := range refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return err
+		}
+
+		// clip to just outside the tile, so that the edges of large cells
+		// are densified where they are seen
+		ring := clipBounds(b, pad).DensifiedRing(opts.Densify)
+
+		coords := make([][]float64, len(ring))
+		for i, p := range ring {
+			coords[i] = []float64{p[0], p[1]}
+		}
+
+		err = geom.DrawCoordLine(gc, coords, style.LineWidth, style.LineColor, style.StrokeWidth, style.StrokeColor, toPixel)
+		if err != nil {
+			return err
+		}
+	}
+
+	if style.Label == nil {
+		return nil
+	}
+
+	_, fm, err := setLabelFont(gc, style)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		label := style.Label(ref)
+		if label == "" {
+			continue
+		}
+
+		b, err := ref.Bounds()
+		if err != nil {
+			return err
+		}
+
+		// label the bottom left corner, which stays inside the cell however
+		// the grid is rotated against the tile
+		x, y := toPixel(b.Xmin+size/20, b.Ymin+size/20)
+
+		err = geom.DrawString(gc, []float64{x, y - fm.Descent}, 0, label)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func clipBounds(b, clip nationalgrid.Bounds) nationalgrid.Bounds {
+	return nationalgrid.Bounds{
+		Xmin: math.Max(b.Xmin, clip.Xmin),
+		Ymin: math.Max(b.Ymin, clip.Ymin),
+		Xmax: math.Min(b.Xmax, clip.Xmax),
+		Ymax: math.Min(b.Ymax, clip.Ymax),
+	}
+}
+
+// NewTileHandler serves PNG tiles at /{z}/{x}/{y}.png. Tiles are the same for
+// the same options, so are served with a Cache-Control max-age and an ETag.
+func NewTileHandler(opts TileOptions) http.Handler {
+	return tileHandler(opts.CacheMaxAge, ".png", "image/png", func(z, x, y int) ([]byte, error) {
+		m, err := Tile(z, x, y, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+
+		err = png.Encode(&buf, m)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+// errInvalidTile marks tile coordinates outside the tile pyramid.
+var errInvalidTile = errors.New("invalid tile")
+
+func tileHandler(maxAge int, ext, contentType string, encode func(z, x, y int) ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		z, x, y, err := parseTilePath(r.URL.Path, ext)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		b, err := encode(z, x, y)
+		if errors.Is(err, errInvalidTile) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha1.Sum(b) //nolint:gosec // etags only
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_, _ = w.Write(b)
+	})
+}
+
+// parseTilePath parses the trailing z/x/y.ext of a path.
+func parseTilePath(p, ext string) (int, int, int, error) {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	if len(parts) < 3 || !strings.HasSuffix(parts[len(parts)-1], ext) {
+		return 0, 0, 0, fmt.Errorf("expected /{z}/{x}/{y}%v, got %v", ext, p)
+	}
+
+	parts = parts[len(parts)-3:]
+	parts[2] = strings.TrimSuffix(parts[2], ext)
+
+	var zxy [3]int
+
+	for i, s := range parts {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("expected /{z}/{x}/{y}%v, got %v", ext, p)
+		}
+		zxy[i] = v
+	}
+
+	return zxy[0], zxy[1], zxy[2], nil
+}
@@ -0,0 +1,133 @@
+package render
+
+import (
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func TestTileLevel(t *testing.T) {
+	opts := DefaultTileOptions()
+
+	// tiles over SD91 at increasing zooms
+	tests := map[string]struct {
+		Z, X, Y  int
+		Expected float64
+	}{
+		"z6":  {6, 31, 20, 0},
+		"z7":  {7, 63, 41, nationalgrid.SquareSize},
+		"z10": {10, 505, 330, nationalgrid.SubSquareSize},
+		"z14": {14, 8095, 5287, nationalgrid.KmSquareSize},
+		"z19": {19, 259065, 169212, nationalgrid.HectareSize},
+	}
+
+	for name, tt := range tests {
+		actual := TileLevel(tt.Z, tt.X, tt.Y, opts)
+		if tt.Expected != actual {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, actual)
+		}
+	}
+}
+
+func TestTile(t *testing.T) {
+	opts := DefaultTileOptions()
+
+	m, err := Tile(10, 505, 330, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Bounds() != image.Rect(0, 0, 256, 256) {
+		t.Fatalf("unexpected tile bounds %v", m.Bounds())
+	}
+
+	if opaquePixels(m) == 0 {
+		t.Fatal("expected grid lines on a tile over SD91")
+	}
+
+	err = savePNG("../test-output/tile.png", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mid atlantic is off the grid
+	m, err = Tile(10, 400, 330, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opaquePixels(m) != 0 {
+		t.Fatal("expected an empty tile off the grid")
+	}
+
+	_, err = Tile(23, 0, 0, opts)
+	if err == nil {
+		t.Fatal("expected error beyond the max zoom")
+	}
+
+	_, err = Tile(2, 4, 0, opts)
+	if err == nil {
+		t.Fatal("expected error for x outside the zoom level")
+	}
+}
+
+func TestTileHandler(t *testing.T) {
+	h := NewTileHandler(DefaultTileOptions())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/10/505/330.png", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v %v", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	if rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected image/png, got %v", rec.Header().Get("Content-Type"))
+	}
+
+	if rec.Header().Get("Cache-Control") != "public, max-age=604800" {
+		t.Fatalf("unexpected Cache-Control %v", rec.Header().Get("Cache-Control"))
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/10/505/330.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected %v, got %v", http.StatusNotModified, rec.Code)
+	}
+
+	for _, path := range []string{"/10/505", "/10/505/330.jpg", "/a/505/330.png", "/30/0/0.png"} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%v expected %v, got %v", path, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func opaquePixels(m image.Image) int {
+	n := 0
+
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, a := m.At(x, y).RGBA()
+			if a != 0 {
+				n++
+			}
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,163 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// defaultMaxAttempts is how many points are tried for each sample before a
+// cell is taken to be outside SampleOptions.Within.
+const defaultMaxAttempts = 1000
+
+// Sample is a point drawn from within a grid cell.
+type Sample struct {
+	Point EastingNorthing
+	Ref   GridRef
+}
+
+type SampleOptions struct {
+	// Seed seeds the random source, the same seed giving the same samples.
+	Seed int64
+	// Within constrains samples to a polygon when set.
+	Within Polygon
+	// MaxAttempts is how many points are tried for each sample when Within
+	// is set, 1000 when zero.
+	MaxAttempts int
+}
+
+// RandomSample draws n uniformly random points from each cell, in the order
+// of refs. Cells that no point of SampleOptions.Within can be found in are
+// skipped.
+func RandomSample(refs []GridRef, n int, opts SampleOptions) ([]Sample, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid sample size %v", n)
+	}
+
+	s := newSampler(opts)
+	samples := make([]Sample, 0, n*len(refs))
+
+	for _, ref := range refs {
+		b, err := ref.Bounds()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < n; i++ {
+			p, ok := s.point(b)
+			if !ok {
+				break
+			}
+
+			samples = append(samples, Sample{
+				Point: p,
+				Ref:   ref,
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+// StratifiedSample draws one random point from each cell at precision within
+// each of refs, e.g. one per 1km square of a 10km square. Samples are in the
+// order of refs, then south to north and west to east, each with the ref of
+// the cell it was drawn from. Cells that no point of SampleOptions.Within can
+// be found in are skipped.
+func StratifiedSample(refs []GridRef, precision float64, opts SampleOptions) ([]Sample, error) {
+	err := ValidatePrecision(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSampler(opts)
+
+	var samples []Sample
+
+	for _, ref := range refs {
+		if precision > ref.Precision() {
+			return nil, fmt.Errorf("precision %v is coarser than %v", FormatPrecision(precision), ref)
+		}
+
+		b, err := ref.Bounds()
+		if err != nil {
+			return nil, err
+		}
+
+		cells, err := GridRefsInBounds(b, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cell := range cells {
+			cb, err := cell.Bounds()
+			if err != nil {
+				return nil, err
+			}
+
+			p, ok := s.point(cb)
+			if !ok {
+				continue
+			}
+
+			samples = append(samples, Sample{
+				Point: p,
+				Ref:   cell,
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+type sampler struct {
+	rnd         *rand.Rand
+	within      Polygon
+	withinBox   Bounds
+	maxAttempts int
+}
+
+func newSampler(opts SampleOptions) *sampler {
+	s := &sampler{
+		//nolint:gosec // reproducible survey designs, not security
+		rnd:         rand.New(rand.NewSource(opts.Seed)),
+		within:      opts.Within,
+		withinBox:   opts.Within.Bounds(),
+		maxAttempts: opts.MaxAttempts,
+	}
+
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultMaxAttempts
+	}
+
+	return s
+}
+
+// point returns a random point in b, and within the polygon if set.
+func (s *sampler) point(b Bounds) (EastingNorthing, bool) {
+	var p EastingNorthing
+
+	if s.within == nil {
+		return s.uniform(b), true
+	}
+
+	// cells clear of the polygon are skipped without drawing
+	if b.Xmax <= s.withinBox.Xmin || b.Xmin >= s.withinBox.Xmax || b.Ymax <= s.withinBox.Ymin || b.Ymin >= s.withinBox.Ymax {
+		return p, false
+	}
+
+	for i := 0; i < s.maxAttempts; i++ {
+		p = s.uniform(b)
+		if s.within.Contains(p.Easting, p.Northing) {
+			return p, true
+		}
+	}
+
+	return p, false
+}
+
+func (s *sampler) uniform(b Bounds) EastingNorthing {
+	return EastingNorthing{
+		Easting:  b.Xmin + s.rnd.Float64()*(b.Xmax-b.Xmin),
+		Northing: b.Ymin + s.rnd.Float64()*(b.Ymax-b.Ymin),
+	}
+}
@@ -0,0 +1,135 @@
+package nationalgrid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRandomSample(t *testing.T) {
+	refs := []GridRef{
+		{Square: "SD", SubSquare: "91"},
+		{Square: "SD", SubSquare: "91", Quadrant: NE},
+	}
+
+	opts := SampleOptions{Seed: 42}
+
+	samples, err := RandomSample(refs, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 20 {
+		t.Fatalf("expected %v samples, got %v", 20, len(samples))
+	}
+
+	for _, s := range samples {
+		assertInCell(t, s)
+	}
+
+	again, err := RandomSample(refs, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(samples, again) {
+		t.Fatal("expected the same samples from the same seed")
+	}
+
+	opts.Seed = 43
+
+	other, err := RandomSample(refs, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(samples, other) {
+		t.Fatal("expected different samples from a different seed")
+	}
+}
+
+func TestStratifiedSample(t *testing.T) {
+	refs := []GridRef{{Square: "SD", SubSquare: "91"}}
+
+	samples, err := StratifiedSample(refs, KmSquareSize, SampleOptions{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 100 {
+		t.Fatalf("expected %v samples, got %v", 100, len(samples))
+	}
+
+	seen := map[GridRef]bool{}
+	for _, s := range samples {
+		assertInCell(t, s)
+		seen[s.Ref] = true
+	}
+
+	if len(seen) != 100 {
+		t.Fatalf("expected one sample per 1km square, got %v squares", len(seen))
+	}
+
+	if samples[0].Ref.String() != "SD9010" || samples[99].Ref.String() != "SD9919" {
+		t.Fatalf("expected samples south to north, west to east, got %v ... %v", samples[0].Ref, samples[99].Ref)
+	}
+
+	// the triangle below the diagonal of SD91
+	within := Polygon{{{390000, 410000}, {400000, 410000}, {400000, 420000}, {390000, 410000}}}
+
+	samples, err = StratifiedSample(refs, KmSquareSize, SampleOptions{Seed: 1, Within: within})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 45 squares wholly below the diagonal and 10 it crosses
+	if len(samples) != 55 {
+		t.Fatalf("expected %v samples, got %v", 55, len(samples))
+	}
+
+	for _, s := range samples {
+		if !within.Contains(s.Point.Easting, s.Point.Northing) {
+			t.Fatalf("expected %+v within the polygon", s)
+		}
+	}
+
+	_, err = StratifiedSample(refs, SquareSize, SampleOptions{})
+	if err == nil {
+		t.Fatal("expected error for a precision coarser than the cell")
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	// a square with a square hole
+	p := Polygon{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+		{{4, 4}, {6, 4}, {6, 6}, {4, 6}},
+	}
+
+	tests := map[[2]float64]bool{
+		{1, 1}:  true,
+		{5, 5}:  false,
+		{11, 5}: false,
+		{9, 9}:  true,
+	}
+
+	for pt, expected := range tests {
+		actual := p.Contains(pt[0], pt[1])
+		if expected != actual {
+			t.Fatalf("%v expected %v, got %v", pt, expected, actual)
+		}
+	}
+}
+
+func assertInCell(t *testing.T, s Sample) {
+	t.Helper()
+
+	b, err := s.Ref.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := s.Point
+	if p.Easting < b.Xmin || p.Easting >= b.Xmax || p.Northing < b.Ymin || p.Northing >= b.Ymax {
+		t.Fatalf("expected %+v within %+v", p, b)
+	}
+}
@@ -0,0 +1,361 @@
+// Package server exposes grid reference conversion over HTTP as JSON.
+//
+//	GET  /parse?ref=SD91NW
+//	GET  /ref?ref=SD91NW
+//	GET  /coords?easting=392500&northing=417500&precision=5km
+//	GET  /coords?lat=53.646&lon=-2.114&precision=10km
+//	GET  /bounds?ref=SD91
+//	GET  /neighbours?ref=SD91
+//	GET  /coverage?xmin=387221&ymin=410715&xmax=392221&ymax=415715
+//	POST /coverage?crs=WGS84 with a GeoJSON body
+//
+// Errors are returned as {"error": {"code": "...", "message": "..."}}.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+	geos "github.com/twpayne/go-geos"
+)
+
+// ErrorCode identifies the class of a failed request.
+type ErrorCode string
+
+const (
+	ErrInvalidRef       ErrorCode = "invalid_ref"
+	ErrUnknownSquare    ErrorCode = "unknown_square"
+	ErrInvalidParameter ErrorCode = "invalid_parameter"
+	ErrOutsideGrid      ErrorCode = "outside_grid"
+	ErrInvalidGeoJSON   ErrorCode = "invalid_geojson"
+	ErrMethodNotAllowed ErrorCode = "method_not_allowed"
+)
+
+// maxBodySize limits GeoJSON request bodies.
+const maxBodySize = 10 << 20
+
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	status  int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+type ParseResponse struct {
+	Ref       string  `json:"ref"`
+	Square    string  `json:"square"`
+	SubSquare string  `json:"subSquare,omitempty"`
+	Quadrant  string  `json:"quadrant,omitempty"`
+	Precision float64 `json:"precision"`
+}
+
+type CoordsResponse struct {
+	Ref      string  `json:"ref"`
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+}
+
+type BoundsResponse struct {
+	Ref    string              `json:"ref"`
+	Bounds nationalgrid.Bounds `json:"bounds"`
+}
+
+type NeighboursResponse struct {
+	Ref        string                 `json:"ref"`
+	Neighbours []nationalgrid.GridRef `json:"neighbours"`
+}
+
+type CoverageResponse struct {
+	Coverage map[string][]int `json:"coverage"`
+}
+
+// NewHandler returns a handler serving the conversion endpoints, suitable for
+// mounting under a prefix with http.StripPrefix.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/parse", get(handleParse))
+	mux.HandleFunc("/ref", get(handleRef))
+	mux.HandleFunc("/coords", get(handleCoords))
+	mux.HandleFunc("/bounds", get(handleBounds))
+	mux.HandleFunc("/neighbours", get(handleNeighbours))
+	mux.HandleFunc("/coverage", handleCoverage)
+
+	return mux
+}
+
+type handlerFunc func(r *http.Request) (interface{}, *Error)
+
+func get(fn handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, methodNotAllowed(r))
+			return
+		}
+
+		respond(w, fn, r)
+	}
+}
+
+func respond(w http.ResponseWriter, fn handlerFunc, r *http.Request) {
+	v, apiErr := fn(r)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, e *Error) {
+	writeJSON(w, e.status, ErrorResponse{Error: e})
+}
+
+func newError(status int, code ErrorCode, err error) *Error {
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+		status:  status,
+	}
+}
+
+func methodNotAllowed(r *http.Request) *Error {
+	return newError(http.StatusMethodNotAllowed, ErrMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+}
+
+func parseRef(r *http.Request) (nationalgrid.GridRef, *Error) {
+	ref := strings.ToUpper(strings.ReplaceAll(r.URL.Query().Get("ref"), " ", ""))
+
+	gridRef, err := nationalgrid.ParseGridRef(ref)
+	if err != nil {
+		return gridRef, newError(http.StatusBadRequest, ErrInvalidRef, err)
+	}
+
+	err = nationalgrid.ValidateSquare(gridRef.Square)
+	if err != nil {
+		return gridRef, newError(http.StatusNotFound, ErrUnknownSquare, err)
+	}
+
+	return gridRef, nil
+}
+
+func parseFloat(r *http.Request, name string) (float64, *Error) {
+	v := r.URL.Query().Get(name)
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, newError(http.StatusBadRequest, ErrInvalidParameter, fmt.Errorf("invalid %v %q", name, v))
+	}
+
+	return f, nil
+}
+
+func handleParse(r *http.Request) (interface{}, *Error) {
+	gridRef, apiErr := parseRef(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return ParseResponse{
+		Ref:       gridRef.String(),
+		Square:    gridRef.Square,
+		SubSquare: gridRef.SubSquare,
+		Quadrant:  gridRef.Quadrant.String(),
+		Precision: gridRef.Precision(),
+	}, nil
+}
+
+func handleRef(r *http.Request) (interface{}, *Error) {
+	gridRef, apiErr := parseRef(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	east, north, err := nationalgrid.GetGridLatLon(gridRef.String())
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, ErrInvalidRef, err)
+	}
+
+	return coordsResponse(gridRef, east, north), nil
+}
+
+func handleCoords(r *http.Request) (interface{}, *Error) {
+	var east, north float64
+	var apiErr *Error
+
+	q := r.URL.Query()
+
+	precision := nationalgrid.SubSquareSize
+	if q.Has("precision") {
+		p, err := nationalgrid.ParsePrecision(q.Get("precision"))
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, ErrInvalidParameter, err)
+		}
+		precision = p
+	}
+
+	switch {
+	case q.Has("easting") || q.Has("northing"):
+		east, apiErr = parseFloat(r, "easting")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		north, apiErr = parseFloat(r, "northing")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+
+	case q.Has("lat") || q.Has("lon"):
+		lat, apiErr := parseFloat(r, "lat")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		lon, apiErr := parseFloat(r, "lon")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+
+		osgb := nationalgrid.Location{
+			Type: nationalgrid.WGS84.String(),
+			LatLon: nationalgrid.LatLon{
+				Lat: lat,
+				Lon: lon,
+			},
+		}.ToOSGB36()
+		east, north = osgb.LatLon.Lat, osgb.LatLon.Lon
+
+	default:
+		return nil, newError(http.StatusBadRequest, ErrInvalidParameter, fmt.Errorf("easting and northing or lat and lon are required"))
+	}
+
+	gridRef, err := nationalgrid.GetGridRef(east, north, precision)
+	if err != nil {
+		return nil, newError(http.StatusNotFound, ErrOutsideGrid, err)
+	}
+
+	return coordsResponse(gridRef, east, north), nil
+}
+
+func coordsResponse(gridRef nationalgrid.GridRef, east, north float64) CoordsResponse {
+	latLon := nationalgrid.Location{
+		Type: nationalgrid.OSGB36.String(),
+		LatLon: nationalgrid.LatLon{
+			Lat: east,
+			Lon: north,
+		},
+	}.ToWGS84()
+
+	return CoordsResponse{
+		Ref:      gridRef.String(),
+		Easting:  east,
+		Northing: north,
+		Lat:      latLon.Lat,
+		Lon:      latLon.Lon,
+	}
+}
+
+func handleBounds(r *http.Request) (interface{}, *Error) {
+	gridRef, apiErr := parseRef(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	b, err := gridRef.Bounds()
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, ErrInvalidRef, err)
+	}
+
+	return BoundsResponse{
+		Ref:    gridRef.String(),
+		Bounds: b,
+	}, nil
+}
+
+func handleNeighbours(r *http.Request) (interface{}, *Error) {
+	gridRef, apiErr := parseRef(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	neighbours, err := gridRef.Neighbours()
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, ErrInvalidRef, err)
+	}
+
+	return NeighboursResponse{
+		Ref:        gridRef.String(),
+		Neighbours: neighbours,
+	}, nil
+}
+
+func handleCoverage(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		respond(w, coverageFromBounds, r)
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		respond(w, coverageFromGeoJSON, r)
+	default:
+		writeError(w, methodNotAllowed(r))
+	}
+}
+
+func coverageFromBounds(r *http.Request) (interface{}, *Error) {
+	var b [4]float64
+
+	for i, name := range []string{"xmin", "ymin", "xmax", "ymax"} {
+		v, apiErr := parseFloat(r, name)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		b[i] = v
+	}
+
+	if b[0] > b[2] || b[1] > b[3] {
+		return nil, newError(http.StatusBadRequest, ErrInvalidParameter, fmt.Errorf("invalid bounds %v", b))
+	}
+
+	return CoverageResponse{
+		Coverage: nationalgrid.GetSubSquares(geos.NewBounds(b[0], b[1], b[2], b[3])),
+	}, nil
+}
+
+func coverageFromGeoJSON(r *http.Request) (interface{}, *Error) {
+	crs := nationalgrid.OSGB36
+
+	switch strings.ToUpper(r.URL.Query().Get("crs")) {
+	case "", nationalgrid.OSGB36.String(), "EPSG:27700":
+	case nationalgrid.WGS84.String(), "EPSG:4326":
+		crs = nationalgrid.WGS84
+	default:
+		return nil, newError(http.StatusBadRequest, ErrInvalidParameter, fmt.Errorf("unsupported crs %v", r.URL.Query().Get("crs")))
+	}
+
+	coverage, err := nationalgrid.ReadGeoJSONCoverage(r.Body, crs)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, ErrInvalidGeoJSON, err)
+	}
+
+	return CoverageResponse{
+		Coverage: coverage,
+	}, nil
+}
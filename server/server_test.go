@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+func request(t *testing.T, method, target, body string, v interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	NewHandler().ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("%v expected json content type, got %v", target, ct)
+	}
+
+	err := json.Unmarshal(rec.Body.Bytes(), v)
+	if err != nil {
+		t.Fatalf("%v: %v", target, err)
+	}
+
+	return rec.Code
+}
+
+func TestParse(t *testing.T) {
+	var resp ParseResponse
+	code := request(t, http.MethodGet, "/parse?ref=sd91nw", "", &resp)
+
+	expected := ParseResponse{
+		Ref:       "SD91NW",
+		Square:    "SD",
+		SubSquare: "91",
+		Quadrant:  "NW",
+		Precision: nationalgrid.QuadrantSize,
+	}
+
+	if code != http.StatusOK || !reflect.DeepEqual(expected, resp) {
+		t.Fatalf("expected %v %+v, got %v %+v", http.StatusOK, expected, code, resp)
+	}
+}
+
+func TestRef(t *testing.T) {
+	var resp CoordsResponse
+	code := request(t, http.MethodGet, "/ref?ref=SD91NW", "", &resp)
+
+	if code != http.StatusOK || resp.Easting != 392500 || resp.Northing != 417500 {
+		t.Fatalf("unexpected response %v %+v", code, resp)
+	}
+
+	if resp.Lat < 53 || resp.Lat > 54 || resp.Lon < -3 || resp.Lon > -2 {
+		t.Fatalf("unexpected lat lon %+v", resp)
+	}
+}
+
+func TestCoords(t *testing.T) {
+	tests := map[string]string{
+		"/coords?easting=392500&northing=417500&precision=5km": "SD91NW",
+		"/coords?easting=392500&northing=417500":               "SD91",
+		"/coords?lat=53.646&lon=-2.114&precision=100km":        "SD",
+	}
+
+	for target, expected := range tests {
+		var resp CoordsResponse
+		code := request(t, http.MethodGet, target, "", &resp)
+
+		if code != http.StatusOK || resp.Ref != expected {
+			t.Fatalf("%v expected %v, got %v %+v", target, expected, code, resp)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	var resp BoundsResponse
+	code := request(t, http.MethodGet, "/bounds?ref=SD91", "", &resp)
+
+	expected := nationalgrid.Bounds{
+		Xmin: 390000,
+		Xmax: 400000,
+		Ymin: 410000,
+		Ymax: 420000,
+	}
+
+	if code != http.StatusOK || resp.Bounds != expected {
+		t.Fatalf("expected %+v, got %v %+v", expected, code, resp)
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	var resp NeighboursResponse
+	code := request(t, http.MethodGet, "/neighbours?ref=SD91", "", &resp)
+
+	expected := []string{"SD92", "SE02", "SE01", "SE00", "SD90", "SD80", "SD81", "SD82"}
+
+	actual := make([]string, 0, len(resp.Neighbours))
+	for _, n := range resp.Neighbours {
+		actual = append(actual, n.String())
+	}
+
+	if code != http.StatusOK || !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v %v", expected, code, actual)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	expected := map[string][]int{
+		"sd": {81, 91},
+	}
+
+	var resp CoverageResponse
+	code := request(t, http.MethodGet, "/coverage?xmin=387221&ymin=410715&xmax=392221&ymax=415715", "", &resp)
+
+	if code != http.StatusOK || !reflect.DeepEqual(expected, resp.Coverage) {
+		t.Fatalf("expected %+v, got %v %+v", expected, code, resp)
+	}
+
+	body := `{"type":"LineString","coordinates":[[387221,410715],[392221,415715]]}`
+
+	resp = CoverageResponse{}
+	code = request(t, http.MethodPost, "/coverage", body, &resp)
+
+	if code != http.StatusOK || !reflect.DeepEqual(expected, resp.Coverage) {
+		t.Fatalf("expected %+v, got %v %+v", expected, code, resp)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		Method string
+		Target string
+		Body   string
+		Status int
+		Code   ErrorCode
+	}{
+		{http.MethodGet, "/parse?ref=SD00XX", "", http.StatusBadRequest, ErrInvalidRef},
+		{http.MethodGet, "/ref?ref=ZZ", "", http.StatusNotFound, ErrUnknownSquare},
+		{http.MethodGet, "/coords?easting=x&northing=1", "", http.StatusBadRequest, ErrInvalidParameter},
+		{http.MethodGet, "/coords?easting=1&northing=1&precision=2km", "", http.StatusBadRequest, ErrInvalidParameter},
+		{http.MethodGet, "/coords?easting=-1&northing=1", "", http.StatusNotFound, ErrOutsideGrid},
+		{http.MethodGet, "/coverage?xmin=1", "", http.StatusBadRequest, ErrInvalidParameter},
+		{http.MethodPost, "/coverage", "{", http.StatusBadRequest, ErrInvalidGeoJSON},
+		{http.MethodPost, "/bounds?ref=SD", "", http.StatusMethodNotAllowed, ErrMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		var resp ErrorResponse
+		code := request(t, tt.Method, tt.Target, tt.Body, &resp)
+
+		if code != tt.Status || resp.Error == nil || resp.Error.Code != tt.Code {
+			t.Fatalf("%v %v expected %v %v, got %v %+v", tt.Method, tt.Target, tt.Status, tt.Code, code, resp.Error)
+		}
+	}
+}
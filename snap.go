@@ -0,0 +1,72 @@
+package nationalgrid
+
+import "math"
+
+// SnapToCorner snaps an OSGB36 easting / northing to the nearest corner of
+// the cell containing it at precision, returning the corner and that cell.
+func SnapToCorner(east, north, precision float64) (EastingNorthing, GridRef, error) {
+	return snap(east, north, precision, func(b Bounds) EastingNorthing {
+		return EastingNorthing{
+			Easting:  nearest(east, b.Xmin, b.Xmax),
+			Northing: nearest(north, b.Ymin, b.Ymax),
+		}
+	})
+}
+
+// SnapToCentre snaps an OSGB36 easting / northing to the centre of the cell
+// containing it at precision, returning the centre and that cell.
+func SnapToCentre(east, north, precision float64) (EastingNorthing, GridRef, error) {
+	return snap(east, north, precision, func(b Bounds) EastingNorthing {
+		return EastingNorthing{
+			Easting:  b.Xmin + (b.Xmax-b.Xmin)/2,
+			Northing: b.Ymin + (b.Ymax-b.Ymin)/2,
+		}
+	})
+}
+
+// SnapToEdge snaps an OSGB36 easting / northing to the nearest point on the
+// edge of the cell containing it at precision, returning the point and that
+// cell. A point equally near two edges snaps to the west or east edge.
+func SnapToEdge(east, north, precision float64) (EastingNorthing, GridRef, error) {
+	return snap(east, north, precision, func(b Bounds) EastingNorthing {
+		x := nearest(east, b.Xmin, b.Xmax)
+		y := nearest(north, b.Ymin, b.Ymax)
+
+		if math.Abs(east-x) <= math.Abs(north-y) {
+			return EastingNorthing{
+				Easting:  x,
+				Northing: north,
+			}
+		}
+
+		return EastingNorthing{
+			Easting:  east,
+			Northing: y,
+		}
+	})
+}
+
+func snap(east, north, precision float64, fn func(b Bounds) EastingNorthing) (EastingNorthing, GridRef, error) {
+	var p EastingNorthing
+
+	ref, err := GetGridRef(east, north, precision)
+	if err != nil {
+		return p, ref, err
+	}
+
+	b, err := ref.Bounds()
+	if err != nil {
+		return p, ref, err
+	}
+
+	return fn(b), ref, nil
+}
+
+// nearest returns whichever of lo and hi is nearer v, lo when equally near.
+func nearest(v, lo, hi float64) float64 {
+	if v-lo <= hi-v {
+		return lo
+	}
+
+	return hi
+}
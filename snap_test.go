@@ -0,0 +1,97 @@
+package nationalgrid
+
+import (
+	"testing"
+)
+
+func TestSnap(t *testing.T) {
+	type snapFunc func(east, north, precision float64) (EastingNorthing, GridRef, error)
+
+	tests := map[string]struct {
+		Snap      snapFunc
+		East      float64
+		North     float64
+		Precision float64
+		Expected  EastingNorthing
+		Ref       string
+		Fail      bool
+	}{
+		"corner": {
+			Snap:      SnapToCorner,
+			East:      387221,
+			North:     410715,
+			Precision: KmSquareSize,
+			Expected:  EastingNorthing{387000, 411000},
+			Ref:       "SD8710",
+		},
+		"corner quadrant": {
+			Snap:      SnapToCorner,
+			East:      393700,
+			North:     416100,
+			Precision: QuadrantSize,
+			Expected:  EastingNorthing{395000, 415000},
+			Ref:       "SD91NW",
+		},
+		"centre": {
+			Snap:      SnapToCentre,
+			East:      387221,
+			North:     410715,
+			Precision: SubSquareSize,
+			Expected:  EastingNorthing{385000, 415000},
+			Ref:       "SD81",
+		},
+		"edge west": {
+			Snap:      SnapToEdge,
+			East:      387221,
+			North:     410715,
+			Precision: KmSquareSize,
+			Expected:  EastingNorthing{387000, 410715},
+			Ref:       "SD8710",
+		},
+		"edge north": {
+			Snap:      SnapToEdge,
+			East:      387521,
+			North:     410915,
+			Precision: KmSquareSize,
+			Expected:  EastingNorthing{387521, 411000},
+			Ref:       "SD8710",
+		},
+		"outside": {
+			Snap:      SnapToCentre,
+			East:      -1,
+			North:     0,
+			Precision: SquareSize,
+			Fail:      true,
+		},
+		"precision": {
+			Snap:      SnapToCorner,
+			East:      387221,
+			North:     410715,
+			Precision: 2000,
+			Fail:      true,
+		},
+	}
+
+	for name, tt := range tests {
+		p, ref, err := tt.Snap(tt.East, tt.North, tt.Precision)
+
+		if tt.Fail {
+			if err == nil {
+				t.Fatalf("%v expected error", name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.Expected != p {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.Expected, p)
+		}
+
+		if tt.Ref != ref.String() {
+			t.Fatalf("%v expected %v, got %v", name, tt.Ref, ref)
+		}
+	}
+}
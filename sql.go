@@ -0,0 +1,99 @@
+package nationalgrid
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scan implements sql.Scanner, a NULL column scans to the zero GridRef.
+func (g *GridRef) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*g = GridRef{}
+		return nil
+	case string:
+		return g.UnmarshalText([]byte(v))
+	case []byte:
+		return g.UnmarshalText(v)
+	}
+
+	return fmt.Errorf("unable to scan %T into a GridRef", src)
+}
+
+// Value implements driver.Valuer, storing the canonical string form.
+func (g GridRef) Value() (driver.Value, error) {
+	if g.Square == "" {
+		return nil, nil
+	}
+
+	text, err := g.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return string(text), nil
+}
+
+func (c EastingNorthing) String() string {
+	e := strconv.FormatFloat(c.Easting, 'f', -1, 64)
+	n := strconv.FormatFloat(c.Northing, 'f', -1, 64)
+
+	return e + "," + n
+}
+
+func ParseEastingNorthing(s string) (EastingNorthing, error) {
+	var c EastingNorthing
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return c, fmt.Errorf("an easting northing must be two comma separated numbers %v", s)
+	}
+
+	east, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid easting %v", s)
+	}
+
+	north, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid northing %v", s)
+	}
+
+	return EastingNorthing{
+		Easting:  east,
+		Northing: north,
+	}, nil
+}
+
+// Scan implements sql.Scanner, expecting the form written by Value.
+func (c *EastingNorthing) Scan(src interface{}) error {
+	var s string
+
+	switch v := src.(type) {
+	case nil:
+		*c = EastingNorthing{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unable to scan %T into an EastingNorthing", src)
+	}
+
+	en, err := ParseEastingNorthing(s)
+	if err != nil {
+		return err
+	}
+
+	*c = en
+
+	return nil
+}
+
+// Value implements driver.Valuer, storing "easting,northing".
+func (c EastingNorthing) Value() (driver.Value, error) {
+	return c.String(), nil
+}
@@ -0,0 +1,210 @@
+package nationalgrid
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memDriver is a minimal in-memory database/sql driver, "INSERT" statements
+// append their arguments as a row and any other statement returns every row.
+type memDriver struct {
+	mu   sync.Mutex
+	rows [][]driver.Value
+}
+
+type memConn struct {
+	d *memDriver
+}
+
+type memStmt struct {
+	d     *memDriver
+	query string
+}
+
+type memRows struct {
+	rows [][]driver.Value
+	i    int
+}
+
+func (d *memDriver) Open(string) (driver.Conn, error) {
+	return &memConn{d: d}, nil
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{d: c.d, query: query}, nil
+}
+
+func (c *memConn) Close() error {
+	return nil
+}
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *memStmt) Close() error {
+	return nil
+}
+
+func (s *memStmt) NumInput() int {
+	return strings.Count(s.query, "?")
+}
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	s.d.rows = append(s.d.rows, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *memStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	return &memRows{rows: s.d.rows}, nil
+}
+
+func (r *memRows) Columns() []string {
+	return []string{"ref", "coords"}
+}
+
+func (r *memRows) Close() error {
+	return nil
+}
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+
+	return nil
+}
+
+func init() {
+	sql.Register("nationalgrid-mem", &memDriver{})
+}
+
+func TestGridRefSQL(t *testing.T) {
+	db, err := sql.Open("nationalgrid-mem", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	refs := []GridRef{
+		{Square: "SD"},
+		{Square: "SD", SubSquare: "87"},
+		{Square: "SD", SubSquare: "87", Quadrant: NE},
+	}
+	coords := []EastingNorthing{
+		{Easting: 350000, Northing: 450000},
+		{Easting: 385000, Northing: 475000},
+		{Easting: 387500.5, Northing: 477500.25},
+	}
+
+	for i := range refs {
+		_, err := db.Exec("INSERT INTO refs VALUES (?, ?)", refs[i], coords[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := db.Query("SELECT ref, coords FROM refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var actualRefs []GridRef
+	var actualCoords []EastingNorthing
+
+	for rows.Next() {
+		var g GridRef
+		var c EastingNorthing
+
+		err := rows.Scan(&g, &c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actualRefs = append(actualRefs, g)
+		actualCoords = append(actualCoords, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(refs, actualRefs) {
+		t.Fatalf("expected %+v, got %+v", refs, actualRefs)
+	}
+
+	if !reflect.DeepEqual(coords, actualCoords) {
+		t.Fatalf("expected %+v, got %+v", coords, actualCoords)
+	}
+}
+
+func TestGridRefValue(t *testing.T) {
+	v, err := GridRef{Square: "SD", SubSquare: "87", Quadrant: SW}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != "SD87SW" {
+		t.Fatalf("expected %v, got %v", "SD87SW", v)
+	}
+
+	v, err = GridRef{}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != nil {
+		t.Fatalf("expected nil, got %v", v)
+	}
+
+	_, err = GridRef{Square: "SD", SubSquare: "8X"}.Value()
+	if err == nil {
+		t.Fatal("expected error for invalid grid ref")
+	}
+}
+
+func TestScanInvalid(t *testing.T) {
+	tests := map[string]struct {
+		Dest sql.Scanner
+		Src  interface{}
+	}{
+		"bad ref": {
+			Dest: &GridRef{},
+			Src:  "SD00XX",
+		},
+		"ref type": {
+			Dest: &GridRef{},
+			Src:  int64(87),
+		},
+		"bad coords": {
+			Dest: &EastingNorthing{},
+			Src:  []byte("387221"),
+		},
+		"coords type": {
+			Dest: &EastingNorthing{},
+			Src:  3.5,
+		},
+	}
+
+	for name, tt := range tests {
+		err := tt.Dest.Scan(tt.Src)
+		if err == nil {
+			t.Fatalf("%v expected error", name)
+		}
+	}
+}
@@ -6,8 +6,36 @@ const (
 	SquareSize    = osShpFileSize
 	SubSquareSize = SquareSize / 10
 	QuadrantSize  = SubSquareSize / 2
+	KmSquareSize  = SubSquareSize / 10
+	HectareSize   = KmSquareSize / 10
+	TenMetreSize  = HectareSize / 10
+	MetreSize     = TenMetreSize / 10
 )
 
+// the cell sizes a grid ref can be given at, coarsest first.
+var Precisions = []float64{
+	SquareSize,
+	SubSquareSize,
+	QuadrantSize,
+	KmSquareSize,
+	HectareSize,
+	TenMetreSize,
+	MetreSize,
+}
+
+// the sizes of the quadrants of each grid ref coarser than 1m, coarsest
+// first, e.g. "SDNE" is 50km and "SD8710NE" is 500m.
+var QuadrantPrecisions = []float64{
+	SquareSize / 2,
+	QuadrantSize,
+	KmSquareSize / 2,
+	HectareSize / 2,
+	TenMetreSize / 2,
+}
+
+// maxDigits is the number of digits in a 1m grid ref.
+const maxDigits = 10
+
 var NationalGridSquares = map[string][]float64{
 	"HP": {
 		4,
@@ -0,0 +1,126 @@
+package nationalgrid
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// TilePathScheme maps grid refs to relative, slash separated file paths for
+// datasets tiled on the grid. Template placeholders are
+//
+//	{ref}      the grid ref, e.g. sd8710
+//	{square}   the 100km square, e.g. sd
+//	{e}, {n}   the easting and northing digits, e.g. 87 and 10
+//	{parents}  the refs of every coarser level, e.g. sd/sd81
+//
+// and the template must end in {ref} so that paths can be mapped back.
+type TilePathScheme struct {
+	Template string
+	// Ext is appended to paths, e.g. ".shp".
+	Ext string
+	// Upper writes refs in upper case rather than lower.
+	Upper bool
+}
+
+var (
+	// ColumnTilePaths nests tiles by square then easting, e.g. sd/87/sd8710.shp.
+	ColumnTilePaths = TilePathScheme{
+		Template: "{square}/{e}/{ref}",
+		Ext:      ".shp",
+	}
+	// TreeTilePaths nests tiles under each coarser level, e.g. sd/sd81/sd8710.shp.
+	TreeTilePaths = TilePathScheme{
+		Template: "{parents}/{ref}",
+		Ext:      ".shp",
+	}
+	// FlatTilePaths puts every tile in one directory, e.g. sd8710.shp.
+	FlatTilePaths = TilePathScheme{
+		Template: "{ref}",
+		Ext:      ".shp",
+	}
+)
+
+// Path returns the path of a grid ref's tile.
+func (s TilePathScheme) Path(ref GridRef) (string, error) {
+	if !strings.HasSuffix(s.Template, "{ref}") {
+		return "", fmt.Errorf("tile path template must end in {ref} %v", s.Template)
+	}
+
+	err := validateKnownGridRef(ref.String())
+	if err != nil {
+		return "", err
+	}
+
+	digits := len(ref.SubSquare) / 2
+
+	var parents []string
+	for k := 0; k < digits; k++ {
+		parents = append(parents, ref.Square+ref.SubSquare[:k]+ref.SubSquare[digits:digits+k])
+	}
+	if ref.Quadrant != "" {
+		parents = append(parents, ref.Square+ref.SubSquare)
+	}
+
+	// only the placeholders are cased, literal template text is kept
+	setCase := strings.ToLower
+	if s.Upper {
+		setCase = strings.ToUpper
+	}
+
+	r := strings.NewReplacer(
+		"{ref}", setCase(ref.String()),
+		"{square}", setCase(ref.Square),
+		"{e}", ref.SubSquare[:digits],
+		"{n}", ref.SubSquare[digits:],
+		"{parents}", setCase(strings.Join(parents, "/")),
+	)
+
+	p := r.Replace(s.Template)
+
+	// empty placeholders, e.g. {e} of a square, leave empty path elements
+	return path.Clean(strings.TrimLeft(p, "/")) + s.Ext, nil
+}
+
+// Ref returns the grid ref of a tile path, which must be the path the scheme
+// gives that ref.
+func (s TilePathScheme) Ref(p string) (GridRef, error) {
+	base := strings.TrimSuffix(path.Base(p), s.Ext)
+
+	ref, err := ParseGridRef(strings.ToUpper(base))
+	if err != nil {
+		return ref, err
+	}
+
+	expected, err := s.Path(ref)
+	if err != nil {
+		return GridRef{}, err
+	}
+
+	if path.Clean(p) != expected {
+		return GridRef{}, fmt.Errorf("tile path %v does not match the scheme, expected %v", p, expected)
+	}
+
+	return ref, nil
+}
+
+// PathsInBounds returns the tile paths at precision needed to cover b, in
+// GridRefsInBounds order.
+func (s TilePathScheme) PathsInBounds(b Bounds, precision float64) ([]string, error) {
+	refs, err := GridRefsInBounds(b, precision)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(refs))
+
+	for _, ref := range refs {
+		p, err := s.Path(ref)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,87 @@
+package nationalgrid
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTilePathScheme(t *testing.T) {
+	km := GridRef{Square: "SD", SubSquare: "8710"}
+	quadrant := GridRef{Square: "SD", SubSquare: "91", Quadrant: NW}
+	square := GridRef{Square: "SD"}
+
+	tests := map[string]struct {
+		Scheme   TilePathScheme
+		Ref      GridRef
+		Expected string
+	}{
+		"column":        {ColumnTilePaths, km, "sd/87/sd8710.shp"},
+		"column square": {ColumnTilePaths, square, "sd/sd.shp"},
+		"tree":          {TreeTilePaths, km, "sd/sd81/sd8710.shp"},
+		"tree quadrant": {TreeTilePaths, quadrant, "sd/sd91/sd91nw.shp"},
+		"tree square":   {TreeTilePaths, square, "sd.shp"},
+		"flat":          {FlatTilePaths, km, "sd8710.shp"},
+		"upper":         {TilePathScheme{Template: "{square}/{n}/{ref}", Ext: ".gpkg", Upper: true}, km, "SD/10/SD8710.gpkg"},
+		"literal":       {TilePathScheme{Template: "Data/{parents}/{ref}", Ext: ".shp"}, km, "Data/sd/sd81/sd8710.shp"},
+		"literal upper": {TilePathScheme{Template: "tiles/Grid_{square}/{ref}", Ext: ".shp", Upper: true}, km, "tiles/Grid_SD/SD8710.shp"},
+	}
+
+	for name, tt := range tests {
+		actual, err := tt.Scheme.Path(tt.Ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.Expected != actual {
+			t.Fatalf("%v expected %v, got %v", name, tt.Expected, actual)
+		}
+
+		ref, err := tt.Scheme.Ref(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.Ref != ref {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.Ref, ref)
+		}
+	}
+
+	for _, p := range []string{"sd/88/sd8710.shp", "sd/87/sd871.shp", "sd/87/sd8710.dbf", "zz/87/zz8710.shp"} {
+		_, err := ColumnTilePaths.Ref(p)
+		if err == nil {
+			t.Fatalf("%v expected error", p)
+		}
+	}
+
+	_, err := ColumnTilePaths.Path(GridRef{Square: "ZZ", SubSquare: "8710"})
+	if err == nil {
+		t.Fatal("expected error for an unknown square")
+	}
+
+	_, err = TilePathScheme{Template: "{ref}/{square}"}.Path(km)
+	if err == nil {
+		t.Fatal("expected error for a template not ending in {ref}")
+	}
+}
+
+func TestPathsInBounds(t *testing.T) {
+	b := Bounds{Xmin: 387500, Ymin: 410500, Xmax: 389500, Ymax: 411500}
+
+	actual, err := ColumnTilePaths.PathsInBounds(b, KmSquareSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"sd/87/sd8710.shp",
+		"sd/88/sd8810.shp",
+		"sd/89/sd8910.shp",
+		"sd/87/sd8711.shp",
+		"sd/88/sd8811.shp",
+		"sd/89/sd8911.shp",
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
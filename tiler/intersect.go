@@ -0,0 +1,149 @@
+package tiler
+
+import (
+	"github.com/jonas-p/go-shp"
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+// featureTiles returns the cells of the batch a shape touches. Points belong
+// to the one cell containing them, lines to every cell they touch, including
+// along an edge, and areas to every cell whose inside they overlap.
+func featureTiles(shape shp.Shape, precision float64, batch map[nationalgrid.GridRef]bool) ([]nationalgrid.GridRef, error) {
+	points, parts, area := shapeGeometry(shape)
+
+	if parts == nil {
+		var refs []nationalgrid.GridRef
+		seen := map[nationalgrid.GridRef]bool{}
+
+		for _, p := range points {
+			ref, err := nationalgrid.GetGridRef(p.X, p.Y, precision)
+			if err != nil {
+				// off the grid
+				continue
+			}
+
+			if batch[ref] && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+
+		return refs, nil
+	}
+
+	candidates, err := cellsInBox(shape.BBox(), precision)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []nationalgrid.GridRef
+
+	for _, ref := range candidates {
+		if !batch[ref] {
+			continue
+		}
+
+		b, err := ref.Bounds()
+		if err != nil {
+			return nil, err
+		}
+
+		if partsIntersect(points, parts, area, b) || area && polygonContains(points, parts, b) {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// shapeGeometry returns a shape's points and, for lines and areas, the start
+// of each part. Shapes without usable geometry have no points.
+func shapeGeometry(shape shp.Shape) ([]shp.Point, []int32, bool) {
+	switch s := shape.(type) {
+	case *shp.Point:
+		return []shp.Point{*s}, nil, false
+	case *shp.PointZ:
+		return []shp.Point{{X: s.X, Y: s.Y}}, nil, false
+	case *shp.PointM:
+		return []shp.Point{{X: s.X, Y: s.Y}}, nil, false
+	case *shp.MultiPoint:
+		return s.Points, nil, false
+	case *shp.MultiPointZ:
+		return s.Points, nil, false
+	case *shp.MultiPointM:
+		return s.Points, nil, false
+	case *shp.PolyLine:
+		return s.Points, s.Parts, false
+	case *shp.PolyLineZ:
+		return s.Points, s.Parts, false
+	case *shp.PolyLineM:
+		return s.Points, s.Parts, false
+	case *shp.Polygon:
+		return s.Points, s.Parts, true
+	case *shp.PolygonZ:
+		return s.Points, s.Parts, true
+	case *shp.PolygonM:
+		return s.Points, s.Parts, true
+	case *shp.MultiPatch:
+		return s.Points, s.Parts, true
+	}
+
+	return nil, nil, false
+}
+
+// partsIntersect reports whether any segment of the parts touches b, or for
+// areas passes through the inside of b.
+func partsIntersect(points []shp.Point, parts []int32, area bool, b nationalgrid.Bounds) bool {
+	crosses := b.IntersectsSegment
+	if area {
+		crosses = b.InteriorIntersectsSegment
+	}
+
+	for i, start := range parts {
+		end := int32(len(points))
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+
+		if start < 0 || start >= end || end > int32(len(points)) {
+			continue
+		}
+
+		if end-start == 1 && b.ContainsPoint(points[start].X, points[start].Y) {
+			return true
+		}
+
+		for j := start; j+1 < end; j++ {
+			if crosses([2]float64{points[j].X, points[j].Y}, [2]float64{points[j+1].X, points[j+1].Y}) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// polygonContains reports whether the centre of b is inside the polygon, for
+// cells wholly within an area.
+func polygonContains(points []shp.Point, parts []int32, b nationalgrid.Bounds) bool {
+	var p nationalgrid.Polygon
+
+	for i, start := range parts {
+		end := int32(len(points))
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+
+		if start < 0 || start >= end || end > int32(len(points)) {
+			continue
+		}
+
+		ring := make([][2]float64, 0, end-start)
+		for _, pt := range points[start:end] {
+			ring = append(ring, [2]float64{pt.X, pt.Y})
+		}
+		p = append(p, ring)
+	}
+
+	return p.Contains(b.Xmin+(b.Xmax-b.Xmin)/2, b.Ymin+(b.Ymax-b.Ymin)/2)
+}
@@ -0,0 +1,439 @@
+// Package tiler splits shapefiles into one shapefile per grid cell.
+//
+// Features are streamed from the source and assigned, in parallel, to each
+// cell they touch. Output tiles are written in batches of at most MaxOpen,
+// the source being read once per batch, so memory and open files stay
+// bounded however large the source.
+package tiler
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/jonas-p/go-shp"
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+type Options struct {
+	// Precision is the size of the tiles, one of nationalgrid.Precisions.
+	Precision float64
+	// Paths lays out the tiles under the destination directory.
+	Paths nationalgrid.TilePathScheme
+	// Workers assign features to tiles, runtime.NumCPU() when zero.
+	Workers int
+	// MaxOpen is the most tiles written at once, each holding 3 open files.
+	MaxOpen int
+	// Progress, when set, is called from the calling goroutine as each
+	// feature is handled.
+	Progress func(Progress)
+}
+
+// Progress reports how far through the tiling is.
+type Progress struct {
+	// Pass counts from 1 to Passes, one pass per batch of tiles.
+	Pass   int
+	Passes int
+	// Features is the number of features read in this pass.
+	Features int
+}
+
+// Tile is a written output shapefile.
+type Tile struct {
+	Ref nationalgrid.GridRef
+	// Path is relative to the destination directory.
+	Path     string
+	Features int
+}
+
+// DefaultOptions writes 10km tiles in the sd/87/sd8710.shp layout.
+func DefaultOptions() Options {
+	return Options{
+		Precision: nationalgrid.SubSquareSize,
+		Paths:     nationalgrid.ColumnTilePaths,
+		MaxOpen:   256,
+	}
+}
+
+// TileShapefile splits the shapefile src into a shapefile per cell under
+// dst, copying each feature and its attributes into every cell it touches.
+// Tiles are returned in path order, features keep their source order.
+func TileShapefile(src, dst string, opts Options) ([]Tile, error) {
+	err := nationalgrid.ValidatePrecision(opts.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+
+	if opts.MaxOpen <= 0 {
+		return nil, fmt.Errorf("invalid max open tiles %v", opts.MaxOpen)
+	}
+
+	// the first pass finds the tiles from feature extents alone
+	refs, err := candidateTiles(src, opts.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	passes := (len(refs) + opts.MaxOpen - 1) / opts.MaxOpen
+
+	var tiles []Tile
+
+	for pass := 0; pass < passes; pass++ {
+		end := (pass + 1) * opts.MaxOpen
+		if end > len(refs) {
+			end = len(refs)
+		}
+
+		written, err := tileBatch(src, dst, refs[pass*opts.MaxOpen:end], pass+1, passes, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		tiles = append(tiles, written...)
+	}
+
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i].Path < tiles[j].Path })
+
+	return tiles, nil
+}
+
+// candidateTiles returns the cells containing a point or touching the extent
+// of a line or area.
+func candidateTiles(src string, precision float64) ([]nationalgrid.GridRef, error) {
+	r, err := shp.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	seen := map[nationalgrid.GridRef]bool{}
+	var refs []nationalgrid.GridRef
+
+	for r.Next() {
+		_, shape := r.Shape()
+
+		cells, err := shapeCandidates(shape, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range cells {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	return refs, nil
+}
+
+// shapeCandidates returns the cells a shape may be written to, as
+// featureTiles would choose them.
+func shapeCandidates(shape shp.Shape, precision float64) ([]nationalgrid.GridRef, error) {
+	points, parts, _ := shapeGeometry(shape)
+	if parts != nil {
+		return cellsInBox(shape.BBox(), precision)
+	}
+
+	var refs []nationalgrid.GridRef
+
+	for _, p := range points {
+		ref, err := nationalgrid.GetGridRef(p.X, p.Y, precision)
+		if err != nil {
+			// off the grid
+			continue
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// cellsInBox returns the cells overlapping box, including those only touching
+// it along an edge or at a corner so that features on a grid line reach the
+// tiles either side.
+func cellsInBox(box shp.Box, precision float64) ([]nationalgrid.GridRef, error) {
+	x0, x1 := touchingRange(box.MinX, box.MaxX, precision)
+	y0, y1 := touchingRange(box.MinY, box.MaxY, precision)
+
+	cells := (x1 - x0 + 1) * (y1 - y0 + 1)
+	if cells > nationalgrid.MaxGridRefs {
+		return nil, fmt.Errorf("%v cells of %v exceed the maximum of %v", cells, nationalgrid.FormatPrecision(precision), nationalgrid.MaxGridRefs)
+	}
+
+	var refs []nationalgrid.GridRef
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			ref, err := nationalgrid.GetGridRef((x+0.5)*precision, (y+0.5)*precision, precision)
+			if err != nil {
+				// off the grid
+				continue
+			}
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// touchingRange returns the first and last index of the cells touching lo to
+// hi, edges included.
+func touchingRange(lo, hi, size float64) (float64, float64) {
+	first := math.Floor(lo / size)
+	if first*size == lo {
+		first--
+	}
+
+	return first, math.Floor(hi / size)
+}
+
+type feature struct {
+	index int
+	shape shp.Shape
+	attrs []string
+	refs  []nationalgrid.GridRef
+	err   error
+}
+
+// tileBatch writes the features touching a batch of tiles.
+func tileBatch(src, dst string, batch []nationalgrid.GridRef, pass, passes int, opts Options) ([]Tile, error) {
+	r, err := shp.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	fields := r.Fields()
+
+	inBatch := make(map[nationalgrid.GridRef]bool, len(batch))
+	for _, ref := range batch {
+		inBatch[ref] = true
+	}
+
+	// tokens bound the features in flight, so out of order results held
+	// for writing in source order can't grow without limit
+	tokens := make(chan struct{}, 4*opts.Workers)
+	jobs := make(chan *feature, opts.Workers)
+	results := make(chan *feature, opts.Workers)
+	done := make(chan struct{})
+
+	var all, workers sync.WaitGroup
+
+	// stop and wait for the goroutines before the deferred r.Close
+	defer func() {
+		close(done)
+		all.Wait()
+	}()
+
+	var readErr error
+
+	all.Add(1)
+	go func() {
+		defer all.Done()
+		defer close(jobs)
+
+		for i := 0; r.Next(); i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			_, shape := r.Shape()
+
+			f := &feature{index: i, shape: shape}
+			for n := range fields {
+				f.attrs = append(f.attrs, r.Attribute(n))
+			}
+
+			select {
+			case jobs <- f:
+			case <-done:
+				return
+			}
+		}
+
+		readErr = r.Err()
+	}()
+
+	for w := 0; w < opts.Workers; w++ {
+		all.Add(1)
+		workers.Add(1)
+
+		go func() {
+			defer all.Done()
+			defer workers.Done()
+
+			for f := range jobs {
+				f.refs, f.err = featureTiles(f.shape, opts.Precision, inBatch)
+
+				select {
+				case results <- f:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	all.Add(1)
+	go func() {
+		defer all.Done()
+
+		workers.Wait()
+		close(results)
+	}()
+
+	writers := map[nationalgrid.GridRef]*tileWriter{}
+	pending := map[int]*feature{}
+	next := 0
+
+	closeAll := func() error {
+		var err error
+		for _, w := range writers {
+			cerr := w.close()
+			if err == nil {
+				err = cerr
+			}
+		}
+
+		return err
+	}
+
+	for f := range results {
+		pending[f.index] = f
+
+		for pending[next] != nil {
+			f := pending[next]
+			delete(pending, next)
+			next++
+			<-tokens
+
+			if f.err != nil {
+				_ = closeAll()
+				return nil, f.err
+			}
+
+			for _, ref := range f.refs {
+				w := writers[ref]
+				if w == nil {
+					w, err = newTileWriter(dst, ref, r.GeometryType, fields, opts.Paths)
+					if err != nil {
+						_ = closeAll()
+						return nil, err
+					}
+					writers[ref] = w
+				}
+
+				err = w.write(f.shape, f.attrs)
+				if err != nil {
+					_ = closeAll()
+					return nil, err
+				}
+			}
+
+			if opts.Progress != nil {
+				opts.Progress(Progress{Pass: pass, Passes: passes, Features: next})
+			}
+		}
+	}
+
+	err = closeAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// results is closed once the reader has finished
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	tiles := make([]Tile, 0, len(writers))
+	for ref, w := range writers {
+		tiles = append(tiles, Tile{Ref: ref, Path: w.path, Features: w.features})
+	}
+
+	return tiles, nil
+}
+
+type tileWriter struct {
+	w        *shp.Writer
+	base     string
+	path     string
+	features int
+}
+
+func newTileWriter(dst string, ref nationalgrid.GridRef, t shp.ShapeType, fields []shp.Field, paths nationalgrid.TilePathScheme) (*tileWriter, error) {
+	p, err := paths.Path(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(p) != ".shp" {
+		return nil, fmt.Errorf("tile paths must end in .shp %v", p)
+	}
+
+	full := filepath.Join(dst, filepath.FromSlash(p))
+
+	err = os.MkdirAll(filepath.Dir(full), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := shp.Create(full, t)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.SetFields(fields)
+	if err != nil {
+		// Close copes with the missing dbf, releasing the shp and shx
+		w.Close()
+		os.Remove(full)
+		os.Remove(full[:len(full)-len(".shp")] + ".shx")
+		return nil, err
+	}
+
+	return &tileWriter{
+		w:    w,
+		base: full[:len(full)-len(".shp")],
+		path: p,
+	}, nil
+}
+
+func (t *tileWriter) write(shape shp.Shape, attrs []string) error {
+	row := int(t.w.Write(shape))
+	t.features++
+
+	for i, v := range attrs {
+		err := t.w.WriteAttribute(row, i, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *tileWriter) close() error {
+	t.w.Close()
+
+	// go-shp v0.1.1 writes the dbf as "<base>dbf", missing the dot
+	return os.Rename(t.base+"dbf", t.base+".dbf")
+}
@@ -0,0 +1,251 @@
+package tiler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonas-p/go-shp"
+	nationalgrid "github.com/rockwell-uk/go-nationalgrid"
+)
+
+// writeFixture writes a shapefile with a name attribute per shape.
+func writeFixture(t *testing.T, path string, shapeType shp.ShapeType, shapes []shp.Shape, names []string) {
+	t.Helper()
+
+	w, err := shp.Create(path, shapeType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.SetFields([]shp.Field{shp.StringField("name", 16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range shapes {
+		row := w.Write(s)
+
+		err = w.WriteAttribute(int(row), 0, names[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.Close()
+
+	base := path[:len(path)-len(".shp")]
+	err = os.Rename(base+"dbf", base+".dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readNames returns the name attribute of each feature in a shapefile.
+func readNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	r, err := shp.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var names []string
+	for r.Next() {
+		// go-shp pads short values with NULs
+		names = append(names, strings.TrimRight(r.Attribute(0), "\x00"))
+	}
+
+	return names
+}
+
+func polygon(points ...shp.Point) shp.Shape {
+	return (*shp.Polygon)(shp.NewPolyLine([][]shp.Point{points}))
+}
+
+func TestTileShapefilePolygons(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "areas.shp")
+
+	writeFixture(t, src, shp.POLYGON, []shp.Shape{
+		// covers SD81 without an edge crossing it
+		polygon(shp.Point{X: 375000, Y: 405000}, shp.Point{X: 375000, Y: 425000}, shp.Point{X: 395000, Y: 425000}, shp.Point{X: 395000, Y: 405000}, shp.Point{X: 375000, Y: 405000}),
+		// its hypotenuse passes by SD92, its other sides lie on grid lines so
+		// leave out SD70, SD71, SD72, SD80 and SD90
+		polygon(shp.Point{X: 380000, Y: 410000}, shp.Point{X: 380000, Y: 429000}, shp.Point{X: 399000, Y: 410000}, shp.Point{X: 380000, Y: 410000}),
+	}, []string{"square", "triangle"})
+
+	dst := t.TempDir()
+
+	tiles, err := TileShapefile(src, dst, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		"sd/7/sd70.shp": 1, "sd/7/sd71.shp": 1, "sd/7/sd72.shp": 1,
+		"sd/8/sd80.shp": 1, "sd/8/sd81.shp": 2, "sd/8/sd82.shp": 2,
+		"sd/9/sd90.shp": 1, "sd/9/sd91.shp": 2, "sd/9/sd92.shp": 1,
+	}
+
+	actual := map[string]int{}
+	for _, tile := range tiles {
+		actual[tile.Path] = tile.Features
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+
+	names := readNames(t, filepath.Join(dst, "sd", "8", "sd81.shp"))
+	if !reflect.DeepEqual([]string{"square", "triangle"}, names) {
+		t.Fatalf("expected the features in source order, got %+v", names)
+	}
+}
+
+func TestTileShapefileLinesAndPoints(t *testing.T) {
+	dir := t.TempDir()
+
+	lines := filepath.Join(dir, "lines.shp")
+	writeFixture(t, lines, shp.POLYLINE, []shp.Shape{
+		shp.NewPolyLine([][]shp.Point{{{X: 381000, Y: 411000}, {X: 398000, Y: 411500}}}),
+	}, []string{"road"})
+
+	edge := filepath.Join(dir, "edge.shp")
+	writeFixture(t, edge, shp.POLYLINE, []shp.Shape{
+		shp.NewPolyLine([][]shp.Point{{{X: 381000, Y: 410000}, {X: 389000, Y: 410000}}}),
+	}, []string{"on a grid line"})
+
+	points := filepath.Join(dir, "points.shp")
+	writeFixture(t, points, shp.POINT, []shp.Shape{
+		&shp.Point{X: 385000, Y: 415000},
+		&shp.Point{X: 387654, Y: 410432},
+		&shp.Point{X: -1000, Y: 415000},
+	}, []string{"a", "b", "off grid"})
+
+	tests := map[string]struct {
+		src      string
+		opts     Options
+		expected []Tile
+	}{
+		"lines": {
+			src:  lines,
+			opts: DefaultOptions(),
+			expected: []Tile{
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "81"}, Path: "sd/8/sd81.shp", Features: 1},
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "91"}, Path: "sd/9/sd91.shp", Features: 1},
+			},
+		},
+		"edge": {
+			src:  edge,
+			opts: DefaultOptions(),
+			expected: []Tile{
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "80"}, Path: "sd/8/sd80.shp", Features: 1},
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "81"}, Path: "sd/8/sd81.shp", Features: 1},
+			},
+		},
+		"points": {
+			src: points,
+			opts: Options{
+				Precision: nationalgrid.KmSquareSize,
+				Paths:     nationalgrid.FlatTilePaths,
+				Workers:   2,
+				MaxOpen:   1,
+			},
+			expected: []Tile{
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "8515"}, Path: "sd8515.shp", Features: 1},
+				{Ref: nationalgrid.GridRef{Square: "SD", SubSquare: "8710"}, Path: "sd8710.shp", Features: 1},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := TileShapefile(tt.src, t.TempDir(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Fatalf("%v expected %+v, got %+v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestTileShapefileProgress(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "points.shp")
+
+	var shapes []shp.Shape
+	var names []string
+	for i := 0; i < 20; i++ {
+		shapes = append(shapes, &shp.Point{X: 381000 + float64(i)*1000, Y: 411000})
+		names = append(names, "p")
+	}
+	writeFixture(t, src, shp.POINT, shapes, names)
+
+	opts := DefaultOptions()
+	opts.Precision = nationalgrid.KmSquareSize
+	opts.MaxOpen = 8
+	opts.Workers = 4
+
+	var last Progress
+	calls := 0
+	opts.Progress = func(p Progress) {
+		calls++
+		last = p
+	}
+
+	tiles, err := TileShapefile(src, t.TempDir(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tiles) != 20 {
+		t.Fatalf("expected 20 tiles, got %v", len(tiles))
+	}
+
+	// 20 tiles in batches of 8 take 3 passes over the 20 features
+	expected := Progress{Pass: 3, Passes: 3, Features: 20}
+	if last != expected || calls != 60 {
+		t.Fatalf("expected %+v after 60 calls, got %+v after %v", expected, last, calls)
+	}
+}
+
+func TestTileShapefileErrors(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Precision = 2000
+
+	_, err := TileShapefile("missing.shp", t.TempDir(), opts)
+	if err == nil {
+		t.Fatalf("expected an invalid precision error")
+	}
+
+	_, err = TileShapefile(filepath.Join(t.TempDir(), "missing.shp"), t.TempDir(), DefaultOptions())
+	if err == nil {
+		t.Fatalf("expected a missing file error")
+	}
+}
+
+func TestNewTileWriterFieldsError(t *testing.T) {
+	dst := t.TempDir()
+	ref := nationalgrid.GridRef{Square: "SD", SubSquare: "81"}
+
+	// a directory where the dbf goes makes SetFields fail
+	err := os.MkdirAll(filepath.Join(dst, "sd", "8", "sd81dbf"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newTileWriter(dst, ref, shp.POINT, nil, nationalgrid.ColumnTilePaths)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	for _, ext := range []string{".shp", ".shx"} {
+		_, err := os.Stat(filepath.Join(dst, "sd", "8", "sd81"+ext))
+		if !os.IsNotExist(err) {
+			t.Fatalf("expected the partial %v to be removed, got %v", ext, err)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package nationalgrid
 
 import (
-	"fmt"
+	"math"
 
 	geos "github.com/twpayne/go-geos"
 )
@@ -37,6 +37,12 @@ type GridRef struct {
 	Quadrant  Quadrant
 }
 
+// EastingNorthing is an OSGB36 National Grid coordinate in metres.
+type EastingNorthing struct {
+	Easting  float64
+	Northing float64
+}
+
 type GridSquare struct {
 	Geom *geos.Geom
 	MinX float64
@@ -44,35 +50,110 @@ type GridSquare struct {
 }
 
 type Bounds struct {
-	Xmin float64
-	Xmax float64
-	Ymin float64
-	Ymax float64
+	Xmin float64 `json:"xmin"`
+	Xmax float64 `json:"xmax"`
+	Ymin float64 `json:"ymin"`
+	Ymax float64 `json:"ymax"`
 }
 
+// ToPolygon returns the bounds as a counter-clockwise WKT polygon, the same
+// as ToWKT.
 func (b Bounds) ToPolygon() string {
-	tl := []float64{
+	return b.ToWKT()
+}
+
+// ring returns the closed, counter-clockwise exterior ring of the bounds
+// starting from the bottom left corner.
+func (b Bounds) ring() [5][2]float64 {
+	bl := [2]float64{
 		b.Xmin,
 		b.Ymin,
 	}
 
-	tr := []float64{
+	br := [2]float64{
 		b.Xmax,
 		b.Ymin,
 	}
 
-	bl := []float64{
-		b.Xmin,
+	tr := [2]float64{
+		b.Xmax,
 		b.Ymax,
 	}
 
-	br := []float64{
-		b.Xmax,
+	tl := [2]float64{
+		b.Xmin,
 		b.Ymax,
 	}
 
-	return fmt.Sprintf(
-		"POLYGON((%v %v, %v %v, %v %v, %v %v, %v %v))",
-		tl[0], tl[1], tr[0], tr[1], br[0], br[1], bl[0], br[1], tl[0], tl[1],
-	)
+	return [5][2]float64{bl, br, tr, tl, bl}
+}
+
+// DensifiedRing returns the counter-clockwise outline with n extra points
+// evenly spaced along each edge, so that the edges stay accurate once
+// reprojected. A negative n adds none.
+func (b Bounds) DensifiedRing(n int) [][2]float64 {
+	if n < 0 {
+		n = 0
+	}
+
+	ring := b.ring()
+
+	points := make([][2]float64, 0, (len(ring)-1)*(n+1)+1)
+
+	for i := 0; i < len(ring)-1; i++ {
+		from, to := ring[i], ring[i+1]
+		for j := 0; j <= n; j++ {
+			f := float64(j) / float64(n+1)
+			points = append(points, [2]float64{
+				from[0] + (to[0]-from[0])*f,
+				from[1] + (to[1]-from[1])*f,
+			})
+		}
+	}
+
+	return append(points, ring[len(ring)-1])
+}
+
+// ContainsPoint reports whether x, y is inside b, edges included.
+func (b Bounds) ContainsPoint(x, y float64) bool {
+	return x >= b.Xmin && x <= b.Xmax && y >= b.Ymin && y <= b.Ymax
+}
+
+// IntersectsSegment reports whether the segment a c touches b, edges
+// included.
+func (b Bounds) IntersectsSegment(a, c [2]float64) bool {
+	return b.clipSegment(a, c, false)
+}
+
+// InteriorIntersectsSegment reports whether the segment a c passes through
+// the inside of b, a segment only along or touching an edge not counting.
+func (b Bounds) InteriorIntersectsSegment(a, c [2]float64) bool {
+	return b.clipSegment(a, c, true)
+}
+
+// clipSegment clips the segment a c to b, Liang-Barsky, reporting whether
+// any of it is left. Open clips to the inside of b, excluding the edges.
+func (b Bounds) clipSegment(a, c [2]float64, open bool) bool {
+	dx, dy := c[0]-a[0], c[1]-a[1]
+	t0, t1 := 0.0, 1.0
+
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q > 0 || !open && q == 0
+		}
+
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+
+		return t0 < t1 || !open && t0 == t1
+	}
+
+	return clip(-dx, a[0]-b.Xmin) &&
+		clip(dx, b.Xmax-a[0]) &&
+		clip(-dy, a[1]-b.Ymin) &&
+		clip(dy, b.Ymax-a[1])
 }
@@ -8,8 +8,8 @@ import (
 func ValidateGridRef(ref string) error {
 	l := len(ref)
 
-	if l < 2 || l > 6 {
-		return fmt.Errorf("a valid grid ref must be 2, 4, or 6 chars in length %v", ref)
+	if l < 2 {
+		return fmt.Errorf("a valid grid ref must be 2 letters followed by an even number of up to %v digits %v", maxDigits, ref)
 	}
 
 	validateSquare := func(ref string) error {
@@ -21,58 +21,58 @@ func ValidateGridRef(ref string) error {
 	}
 
 	validateSubSquare := func(ref string) error {
-		_, err := strconv.Atoi(ref)
-		if err != nil {
-			return fmt.Errorf("the third and fourth characters of a gridref must be numeric %v", ref)
+		if len(ref)%2 != 0 || len(ref) > maxDigits {
+			return fmt.Errorf("a valid grid ref must be 2 letters followed by an even number of up to %v digits %v", maxDigits, ref)
+		}
+		for _, c := range ref {
+			if c < '0' || c > '9' {
+				return fmt.Errorf("the characters following the square of a gridref must be numeric %v", ref)
+			}
 		}
 		return nil
 	}
 
 	validateQuadrant := func(ref string) error {
 		if ref != string(NE) && ref != string(NW) && ref != string(SE) && ref != string(SW) {
-			return fmt.Errorf("the fifth and sixth characters of a gridref must be a valid quadrant %v", ref)
+			return fmt.Errorf("the last two characters of a gridref must be a valid quadrant %v", ref)
 		}
 		return nil
 	}
 
-	switch l {
-	case 2:
-		err := validateSquare(ref)
-		if err != nil {
-			return err
-		}
+	square := ref[0:2]
+	err := validateSquare(square)
+	if err != nil {
+		return err
+	}
 
-	case 4:
-		square := ref[0:2]
-		err := validateSquare(square)
-		if err != nil {
-			return err
-		}
-		subsquare := ref[2:4]
-		err = validateSubSquare(subsquare)
-		if err != nil {
-			return err
-		}
+	subsquare := ref[2:]
 
-	case 6:
-		square := ref[0:2]
-		err := validateSquare(square)
-		if err != nil {
-			return err
-		}
-		subsquare := ref[2:4]
-		err = validateSubSquare(subsquare)
-		if err != nil {
-			return err
-		}
-		quadrant := ref[4:6]
+	if hasQuadrant(ref) {
+		quadrant := ref[l-2:]
 		err = validateQuadrant(quadrant)
 		if err != nil {
 			return err
 		}
+
+		subsquare = ref[2 : l-2]
+		if len(subsquare) >= maxDigits {
+			return fmt.Errorf("a quadrant cannot follow a 1m grid ref %v", ref)
+		}
 	}
 
-	return nil
+	return validateSubSquare(subsquare)
+}
+
+// hasQuadrant reports whether a grid ref ends in two letters after the square.
+func hasQuadrant(ref string) bool {
+	l := len(ref)
+	if l < 4 {
+		return false
+	}
+
+	_, err := strconv.Atoi(ref[l-1:])
+
+	return err != nil
 }
 
 func ValidateSquare(square string) error {